@@ -0,0 +1,331 @@
+package hawk
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// ErrForbidden is returned by Authorizer when an authenticated request is not permitted.
+var ErrForbidden = errors.New("Access denied.")
+
+// ErrBodyTooLarge is returned by Authorizer when the request body is larger than MaxBodySize.
+var ErrBodyTooLarge = errors.New("Request body too large.")
+
+var errBodyRead = errors.New("Failed to read request body.")
+
+// AuthInfo holds the result of a successful authentication.
+type AuthInfo struct {
+	Type       AuthType
+	Credential *Credential
+	// Artifacts are the artifacts of the request. For a bewit, the TimeStamp is the expiration time.
+	Artifacts *Option
+}
+
+type authInfoKey struct{}
+
+// NewContext returns a new Context that carries the AuthInfo.
+func NewContext(ctx context.Context, info *AuthInfo) context.Context {
+	return context.WithValue(ctx, authInfoKey{}, info)
+}
+
+// FromContext returns the AuthInfo stored in ctx, if any.
+func FromContext(ctx context.Context) (*AuthInfo, bool) {
+	info, ok := ctx.Value(authInfoKey{}).(*AuthInfo)
+	return info, ok
+}
+
+// Rule is an access rule of Authorizer.
+type Rule struct {
+	// Methods are the methods the rule applies to, e.g. "GET". If empty, it applies to any method.
+	Methods []string
+	// Path is the pattern of the request path, with the syntax of path.Match, e.g. "/reports/*".
+	// A trailing "/**" matches any path below the prefix, e.g. "/reports/**" matches "/reports/2020/1".
+	// If empty, it matches any path. The path is cleaned before it is matched, as an upstream would resolve it,
+	// e.g. "/reports/../admin" is matched as "/admin".
+	Path string
+	// Scopes are the scopes the credential must have, all of them.
+	Scopes []string
+	// Allow is an optional predicate, evaluated after the scopes.
+	Allow func(req *http.Request, info *AuthInfo) bool
+	// AllowBewit accepts bewit authentication for the route. Otherwise, only the Authorization header is accepted.
+	AllowBewit bool
+	// RequirePayloadHash rejects requests without a payload hash in the header, unless the PayloadTrailer is verified,
+	// by Handler, or by Server.VerifyTrailer before Authorize.
+	// The hash in the header is verified against the body only if the Server has the payload.
+	RequirePayloadHash bool
+}
+
+// Match reports whether the rule applies to the request.
+func (r *Rule) Match(req *http.Request) bool {
+	if len(r.Methods) > 0 {
+		found := false
+		for _, m := range r.Methods {
+			if strings.EqualFold(m, req.Method) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return matchPath(r.Path, cleanPath(req.URL.Path))
+}
+
+// cleanPath returns the canonical form of the request path, with the trailing slash kept.
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	if p[0] != '/' {
+		p = "/" + p
+	}
+	c := path.Clean(p)
+	if p[len(p)-1] == '/' && c != "/" {
+		c += "/"
+	}
+	return c
+}
+
+func matchPath(pattern, p string) bool {
+	if pattern == "" {
+		return true
+	}
+	if prefix := strings.TrimSuffix(pattern, "/**"); prefix != pattern {
+		if p == prefix {
+			return true
+		}
+		// match the prefix segment by segment, so that patterns are allowed in it.
+		n := strings.Count(prefix, "/")
+		i := 0
+		for j := 0; j <= n; j++ {
+			k := strings.IndexByte(p[i:], '/')
+			if k < 0 {
+				return false
+			}
+			i += k + 1
+		}
+		ok, _ := path.Match(prefix, p[:i-1])
+		return ok
+	}
+	ok, _ := path.Match(pattern, p)
+	return ok
+}
+
+// Authorizer authenticates requests with a Server and checks them against access rules.
+//
+// The first rule matching the request applies. Requests matching no rule are rejected.
+type Authorizer struct {
+	Server *Server
+	Rules  []Rule
+	// ErrorHandler writes the response for a rejected request.
+	// If nil, it responds 429 with Retry-After for a LockedOutError, 403 for ErrForbidden,
+	// 413 for ErrBodyTooLarge, 401 with the timestamp challenge of a StaleTimestampError,
+	// and 401 with "WWW-Authenticate: Hawk" otherwise.
+	ErrorHandler func(w http.ResponseWriter, req *http.Request, err error)
+	// MaxBodySize, if > 0, makes Handler read the body of the requests authenticated with the Authorization header,
+	// up to MaxBodySize bytes, and verify it against the payload hash with the request Content-Type.
+	// A larger body is rejected with ErrBodyTooLarge. The next handler reads the bytes which have been verified,
+	// and can read them again with the GetBody of the request.
+	// The Payload of the Server is not used.
+	MaxBodySize int64
+}
+
+// NewAuthorizer initializes a new Authorizer.
+func NewAuthorizer(s *Server, rules ...Rule) *Authorizer {
+	return &Authorizer{
+		Server: s,
+		Rules:  rules,
+	}
+}
+
+// Authenticate authenticates the request as allowed by the matching rule, and authorizes it.
+func (a *Authorizer) Authenticate(req *http.Request) (*AuthInfo, error) {
+	return a.authenticate(req, a.Server.Payload, a.Server.Payload != "", false)
+}
+
+// authenticate authenticates and authorizes the request. trailer reports whether the PayloadTrailer of the request
+// is verified before it is handed over, which satisfies RequirePayloadHash.
+func (a *Authorizer) authenticate(req *http.Request, payload string, hasPayload, trailer bool) (*AuthInfo, error) {
+	rule := a.rule(req)
+	if rule == nil {
+		return nil, ErrForbidden
+	}
+
+	info := &AuthInfo{Type: Header}
+	var err error
+	if _, n := bewitParam(req.URL.RawQuery); rule.AllowBewit && n > 0 && req.Header.Get("Authorization") == "" {
+		info.Type = Bewit
+		info.Credential, info.Artifacts, err = a.Server.AuthenticateBewitArtifacts(req)
+	} else {
+		info.Credential, info.Artifacts, err = a.Server.authenticate(req, payload, hasPayload)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := authorize(rule, req, info, trailer); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// Authorize checks an authenticated request against the rules.
+func (a *Authorizer) Authorize(req *http.Request, info *AuthInfo) error {
+	rule := a.rule(req)
+	if rule == nil {
+		return ErrForbidden
+	}
+	return authorize(rule, req, info, verifiesTrailer(req))
+}
+
+func authorize(rule *Rule, req *http.Request, info *AuthInfo, trailer bool) error {
+	if info.Type == Bewit && !rule.AllowBewit {
+		return ErrForbidden
+	}
+	if rule.RequirePayloadHash && info.Type == Header && info.Artifacts.Hash == "" && !trailer {
+		return errors.New("Missing required payload hash.")
+	}
+	for _, scope := range rule.Scopes {
+		if !info.Credential.HasScope(scope) {
+			return ErrForbidden
+		}
+	}
+	if rule.Allow != nil && !rule.Allow(req, info) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+func (a *Authorizer) rule(req *http.Request) *Rule {
+	for i := range a.Rules {
+		if a.Rules[i].Match(req) {
+			return &a.Rules[i]
+		}
+	}
+	return nil
+}
+
+// Handler returns a middleware which passes authorized requests to next, with the AuthInfo in the context.
+// The body of a request which announces the PayloadTrailer, and has no payload hash in the header,
+// is verified against the trailer, see Server.VerifyTrailer. Otherwise, it is verified against the header.
+// If MaxBodySize is set, the body is read and verified before next is called.
+// If not, the body verified against a trailer is verified as next reads it, and other bodies are not verified.
+func (a *Authorizer) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		info, err := a.authenticateRequest(req)
+		if err != nil {
+			a.error(w, req, err)
+			return
+		}
+		next.ServeHTTP(w, req.WithContext(NewContext(req.Context(), info)))
+	})
+}
+
+// authenticateRequest authenticates and authorizes the request, with its body as described by Handler.
+func (a *Authorizer) authenticateRequest(req *http.Request) (*AuthInfo, error) {
+	authz := req.Header.Get("Authorization")
+	if authz != "" && hasPayloadTrailer(req) && parseHawkHeader(authz)["hash"] == "" {
+		return a.authenticateTrailer(req)
+	}
+	if a.MaxBodySize <= 0 || authz == "" {
+		return a.Authenticate(req)
+	}
+
+	body, err := readLimitedBody(req, a.MaxBodySize)
+	if err != nil {
+		return nil, err
+	}
+	setBody(req, body)
+	return a.authenticate(req, string(body), true, false)
+}
+
+// authenticateTrailer authenticates and authorizes a request whose payload hash is in the PayloadTrailer.
+func (a *Authorizer) authenticateTrailer(req *http.Request) (*AuthInfo, error) {
+	info, err := a.authenticate(req, "", false, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.Server.VerifyTrailer(req, info.Credential, info.Artifacts); err != nil {
+		return nil, err
+	}
+	if a.MaxBodySize <= 0 {
+		return info, nil
+	}
+
+	tr := req.Body.(*trailerReader)
+	body, err := readLimitedBody(req, a.MaxBodySize)
+	if err == errBodyRead && tr.err != nil {
+		// the error of the verification of the trailer
+		err = tr.err
+	}
+	if err != nil {
+		return nil, err
+	}
+	setBody(req, body)
+	return info, nil
+}
+
+// setBody replaces the body of the request with the bytes which have been read and verified.
+func setBody(req *http.Request, body []byte) {
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(body)), nil
+	}
+	req.ContentLength = int64(len(body))
+}
+
+// readLimitedBody reads the body of the request, up to limit bytes.
+func readLimitedBody(req *http.Request, limit int64) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.ContentLength > limit {
+		return nil, ErrBodyTooLarge
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(req.Body, limit+1))
+	if err != nil {
+		return nil, errBodyRead
+	}
+	if int64(len(body)) > limit {
+		return nil, ErrBodyTooLarge
+	}
+	return body, nil
+}
+
+func (a *Authorizer) error(w http.ResponseWriter, req *http.Request, err error) {
+	if a.ErrorHandler != nil {
+		a.ErrorHandler(w, req, err)
+		return
+	}
+
+	if e, ok := err.(*LockedOutError); ok {
+		w.Header().Set("Retry-After", e.RetryAfterHeader())
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	}
+	if e, ok := err.(*StaleTimestampError); ok {
+		w.Header().Set("WWW-Authenticate", e.WWWAuthenticateHeader())
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	switch err {
+	case ErrForbidden:
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	case ErrBodyTooLarge:
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	case errBodyRead:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("WWW-Authenticate", "Hawk")
+	http.Error(w, err.Error(), http.StatusUnauthorized)
+}
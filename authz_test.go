@@ -0,0 +1,208 @@
+package hawk
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_matchPath(t *testing.T) {
+	for _, tc := range []struct {
+		pattern string
+		path    string
+		expect  bool
+	}{
+		{pattern: "", path: "/any", expect: true},
+		{pattern: "/reports", path: "/reports", expect: true},
+		{pattern: "/reports", path: "/reports/1", expect: false},
+		{pattern: "/reports/*", path: "/reports/1", expect: true},
+		{pattern: "/reports/*", path: "/reports/1/2", expect: false},
+		{pattern: "/reports/**", path: "/reports", expect: true},
+		{pattern: "/reports/**", path: "/reports/2020/1", expect: true},
+		{pattern: "/reports/**", path: "/reportsx/1", expect: false},
+		{pattern: "/users/*/reports/**", path: "/users/a/reports/1/2", expect: true},
+		{pattern: "/users/*/reports/**", path: "/users/a/b/reports/1", expect: false},
+		{pattern: "/**", path: "/a/b", expect: true},
+	} {
+		if act := matchPath(tc.pattern, tc.path); act != tc.expect {
+			t.Errorf("matchPath(%q, %q): actual=%v, expect=%v", tc.pattern, tc.path, act, tc.expect)
+		}
+	}
+}
+
+func TestRule_Match(t *testing.T) {
+	r := &Rule{Path: "/public/**"}
+	for _, tc := range []struct {
+		path   string
+		expect bool
+	}{
+		{path: "/public/a", expect: true},
+		{path: "/public/a/../b", expect: true},
+		{path: "/public/../admin", expect: false},
+		{path: "/public/%2e%2e/admin", expect: false},
+		{path: "//public/./a/", expect: true},
+		{path: "/public/..", expect: false},
+	} {
+		req, _ := http.NewRequest("GET", "http://example.com"+tc.path, nil)
+		if act := r.Match(req); act != tc.expect {
+			t.Errorf("Match(%q): actual=%v, expect=%v", tc.path, act, tc.expect)
+		}
+	}
+
+	if act := cleanPath("/a/./b/"); act != "/a/b/" {
+		t.Errorf("unexpected clean path: %s", act)
+	}
+}
+
+func TestAuthorizer_Handler(t *testing.T) {
+	key := "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn"
+	reader := &Credential{ID: "reader", Key: key, Alg: SHA256, Scopes: []string{"reports:read"}}
+	writer := &Credential{ID: "writer", Key: key, Alg: SHA256, Scopes: []string{"reports:read", "reports:write"}}
+	creds := map[string]*Credential{reader.ID: reader, writer.ID: writer}
+
+	s := NewServer(credentialStoreFunc(func(id string) (*Credential, error) { return creds[id], nil }))
+	a := NewAuthorizer(s,
+		Rule{Methods: []string{"GET"}, Path: "/reports/**", Scopes: []string{"reports:read"}, AllowBewit: true},
+		Rule{Methods: []string{"POST"}, Path: "/reports/*", Scopes: []string{"reports:write"}, RequirePayloadHash: true},
+		Rule{Path: "/users/*", Allow: func(req *http.Request, info *AuthInfo) bool {
+			return req.URL.Path == "/users/"+info.Credential.ID
+		}},
+	)
+
+	var got *AuthInfo
+	ts := httptest.NewServer(a.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = FromContext(r.Context())
+	})))
+	defer ts.Close()
+
+	do := func(cred *Credential, method, path string, opt *Option, bewit bool) int {
+		got = nil
+		u := ts.URL + path
+		req, _ := http.NewRequest(method, u, nil)
+		if bewit {
+			req.URL.RawQuery = "bewit=" + NewBewitConfig(cred, time.Minute).GetBewit(u, nil)
+		} else {
+			opt.TimeStamp = time.Now().Unix()
+			opt.Nonce, _ = Nonce(6)
+			h, _ := NewClient(cred, opt).Header(method, u)
+			req.Header.Set("Authorization", h)
+			req.Header.Set("Content-Type", opt.ContentType)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	for _, tc := range []struct {
+		name   string
+		cred   *Credential
+		method string
+		path   string
+		opt    *Option
+		bewit  bool
+		expect int
+	}{
+		{name: "read", cred: reader, method: "GET", path: "/reports/2020/1", expect: 200},
+		{name: "read with bewit", cred: reader, method: "GET", path: "/reports/1", bewit: true, expect: 200},
+		{name: "write without scope", cred: reader, method: "POST", path: "/reports/1", opt: &Option{ContentType: "text/plain", Payload: "x"}, expect: 403},
+		{name: "write", cred: writer, method: "POST", path: "/reports/1", opt: &Option{ContentType: "text/plain", Payload: "x"}, expect: 200},
+		{name: "write without hash", cred: writer, method: "POST", path: "/reports/1", expect: 401},
+		{name: "predicate", cred: reader, method: "DELETE", path: "/users/reader", expect: 200},
+		{name: "predicate rejects", cred: reader, method: "DELETE", path: "/users/writer", expect: 403},
+		{name: "bewit not allowed", cred: reader, method: "GET", path: "/users/reader", bewit: true, expect: 401},
+		{name: "no rule", cred: writer, method: "GET", path: "/admin", expect: 403},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			opt := tc.opt
+			if opt == nil {
+				opt = &Option{}
+			}
+			if act := do(tc.cred, tc.method, tc.path, opt, tc.bewit); act != tc.expect {
+				t.Errorf("unexpected status: actual=%d, expect=%d", act, tc.expect)
+			}
+			if tc.expect == 200 && (got == nil || got.Credential.ID != tc.cred.ID) {
+				t.Errorf("unexpected auth info in context: %+v", got)
+			}
+			if tc.expect != 200 && got != nil {
+				t.Error("handler is called for a rejected request")
+			}
+		})
+	}
+}
+
+type credentialStoreFunc func(id string) (*Credential, error)
+
+func (f credentialStoreFunc) GetCredential(id string) (*Credential, error) {
+	return f(id)
+}
+
+func TestAuthorizer_MaxBodySize(t *testing.T) {
+	cred := &Credential{ID: "123456", Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn", Alg: SHA256}
+	s := NewServer(&staticCredentialStore{cred})
+	a := NewAuthorizer(s, Rule{})
+	a.MaxBodySize = 16
+
+	var form, reread string
+	ts := httptest.NewServer(a.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		form = r.PostForm.Get("a")
+		body, _ := r.GetBody()
+		b, _ := ioutil.ReadAll(body)
+		reread = string(b)
+	})))
+	defer ts.Close()
+
+	do := func(method, contentType, signed string, body io.Reader) (int, string) {
+		opt := &Option{TimeStamp: time.Now().Unix(), ContentType: contentType, Payload: signed}
+		opt.Nonce, _ = Nonce(6)
+		h, _ := NewClient(cred, opt).Header(method, ts.URL+"/resource")
+		req, _ := http.NewRequest(method, ts.URL+"/resource", body)
+		req.Header.Set("Authorization", h)
+		req.Header.Set("Content-Type", contentType)
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		b, _ := ioutil.ReadAll(res.Body)
+		return res.StatusCode, string(b)
+	}
+
+	form, reread = "", ""
+	if code, _ := do("POST", "application/x-www-form-urlencoded", "a=1&b=2", strings.NewReader("a=1&b=2")); code != http.StatusOK {
+		t.Errorf("expected 200, got %d", code)
+	}
+	if form != "1" || reread != "a=1&b=2" {
+		t.Errorf("unexpected body seen by the handler: form=%q, reread=%q", form, reread)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		signed string
+		body   io.Reader
+		expect int
+		msg    string
+	}{
+		{name: "tampered", signed: `{"a":1}`, body: strings.NewReader(`{"a":2}`), expect: http.StatusUnauthorized, msg: "Bad payload hash."},
+		{name: "emptied", signed: `{"a":1}`, body: nil, expect: http.StatusUnauthorized, msg: "Bad payload hash."},
+		{name: "no hash", signed: "", body: strings.NewReader(`{"a":1}`), expect: http.StatusUnauthorized, msg: "Missing required payload hash."},
+		{name: "too large", signed: `{"a":"0123456789"}`, body: strings.NewReader(`{"a":"0123456789"}`), expect: http.StatusRequestEntityTooLarge},
+		{name: "too large, chunked", signed: `{"a":"0123456789"}`, body: ioutil.NopCloser(strings.NewReader(`{"a":"0123456789"}`)), expect: http.StatusRequestEntityTooLarge},
+	} {
+		code, msg := do("POST", "application/json", tc.signed, tc.body)
+		if code != tc.expect || !strings.Contains(msg, tc.msg) {
+			t.Errorf("%s: unexpected response %d %s", tc.name, code, msg)
+		}
+	}
+
+	if code, _ := do("GET", "", "", nil); code != http.StatusOK {
+		t.Errorf("expected 200 for a request without body, got %d", code)
+	}
+}
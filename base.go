@@ -1,12 +1,80 @@
 // Package hawk provides support for Hawk authentication.
 package hawk
 
-import "time"
+import (
+	"errors"
+	"time"
+)
+
+// Errors returned by Server for a credential which can not be used.
+var (
+	ErrCredentialDisabled    = errors.New("Credential disabled.")
+	ErrCredentialExpired     = errors.New("Credential expired.")
+	ErrCredentialNotYetValid = errors.New("Credential not yet valid.")
+)
 
+// Credential is the credential of a client.
+// It holds a slice and a map, so it is not comparable with ==. Use Clone to copy it.
 type Credential struct {
 	ID  string
 	Key string
 	Alg Alg
+
+	// NotBefore and NotAfter limit the validity of the credential (inclusive).
+	// A zero value means no limit.
+	NotBefore time.Time
+	NotAfter  time.Time
+	// Disabled rejects every request made with the credential.
+	Disabled bool
+	// Scopes and Metadata are not interpreted by Server, e.g. the permissions
+	// and the owner (user, tenant) of the credential.
+	Scopes   []string
+	Metadata map[string]string
+	// Signer, if set, computes the MACs instead of the Key, which may be empty.
+	Signer Signer
+}
+
+// Clone returns a copy of the credential, which does not share Scopes and Metadata with it.
+func (c *Credential) Clone() *Credential {
+	if c == nil {
+		return nil
+	}
+	copied := *c
+	if c.Scopes != nil {
+		copied.Scopes = append([]string(nil), c.Scopes...)
+	}
+	if c.Metadata != nil {
+		copied.Metadata = make(map[string]string, len(c.Metadata))
+		for k, v := range c.Metadata {
+			copied.Metadata[k] = v
+		}
+	}
+	return &copied
+}
+
+// HasScope reports whether the credential has the scope.
+func (c *Credential) HasScope(scope string) bool {
+	for _, s := range c.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Valid returns ErrCredentialDisabled, ErrCredentialNotYetValid or ErrCredentialExpired if the credential
+// can not be used at the unix time now.
+func (c *Credential) Valid(now int64) error {
+	if c.Disabled {
+		return ErrCredentialDisabled
+	}
+	if !c.NotBefore.IsZero() && now < c.NotBefore.Unix() {
+		return ErrCredentialNotYetValid
+	}
+	if !c.NotAfter.IsZero() && now > c.NotAfter.Unix() {
+		return ErrCredentialExpired
+	}
+	return nil
 }
 
 type Option struct {
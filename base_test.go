@@ -0,0 +1,48 @@
+package hawk
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCredential_Valid(t *testing.T) {
+	now := time.Unix(1365711458, 0)
+
+	for _, tc := range []struct {
+		cred   Credential
+		expect error
+	}{
+		{cred: Credential{}},
+		{cred: Credential{NotBefore: now, NotAfter: now}},
+		{cred: Credential{NotBefore: now.Add(time.Second)}, expect: ErrCredentialNotYetValid},
+		{cred: Credential{NotAfter: now.Add(-time.Second)}, expect: ErrCredentialExpired},
+		{cred: Credential{Disabled: true, NotAfter: now.Add(-time.Second)}, expect: ErrCredentialDisabled},
+	} {
+		if err := tc.cred.Valid(now.Unix()); err != tc.expect {
+			t.Errorf("unexpected error for %+v: actual=%v, expect=%v", tc.cred, err, tc.expect)
+		}
+	}
+}
+
+func TestCredential_Clone(t *testing.T) {
+	c := &Credential{ID: "a", Scopes: []string{"read"}, Metadata: map[string]string{"tenant": "example"}}
+	copied := c.Clone()
+	copied.Scopes[0] = "admin"
+	copied.Metadata["tenant"] = "other"
+	if c.Scopes[0] != "read" || c.Metadata["tenant"] != "example" {
+		t.Errorf("the copy shares its scopes or metadata: %+v", c)
+	}
+	if (*Credential)(nil).Clone() != nil {
+		t.Error("expected nil")
+	}
+}
+
+func TestCredential_HasScope(t *testing.T) {
+	c := &Credential{Scopes: []string{"read", "write"}}
+	if !c.HasScope("write") {
+		t.Error("expected true, but got false")
+	}
+	if c.HasScope("admin") {
+		t.Error("expected false, but got true")
+	}
+}
@@ -2,7 +2,6 @@ package hawk
 
 import (
 	"encoding/base64"
-	"strconv"
 	"time"
 )
 
@@ -30,7 +29,7 @@ func (b *BewitConfig) GetBewit(url string, clock Clock) string {
 	if b.Credential == nil {
 		return ""
 	}
-	if b.Credential.ID == "" || b.Credential.Key == "" || b.Credential.Alg == 0 {
+	if b.Credential.ID == "" || !hasKey(b.Credential) || b.Credential.Alg == 0 {
 		return ""
 	}
 
@@ -53,9 +52,20 @@ func (b *BewitConfig) GetBewit(url string, clock Clock) string {
 		Method:     "GET",
 		Option:     opt,
 	}
-	mac, _ := m.String()
+	mac, err := m.String()
+	if err != nil {
+		return ""
+	}
 
-	bewit := b.Credential.ID + "\\" + strconv.FormatInt(exp, 10) + "\\" + mac + "\\" + b.Ext
+	buf := getBuffer()
+	defer putBuffer(buf)
+	buf.WriteString(b.Credential.ID)
+	buf.WriteByte('\\')
+	writeInt(buf, exp)
+	buf.WriteByte('\\')
+	buf.WriteString(mac)
+	buf.WriteByte('\\')
+	buf.WriteString(b.Ext)
 
-	return base64.RawURLEncoding.EncodeToString([]byte(bewit))
+	return base64.RawURLEncoding.EncodeToString(buf.Bytes())
 }
@@ -75,3 +75,22 @@ func TestBewitConfig_GetBewit2(t *testing.T) {
 		t.Error("expect result is not null, but got null value")
 	}
 }
+
+func BenchmarkBewitConfig_GetBewit(b *testing.B) {
+	c := &Credential{
+		ID:  "123456",
+		Key: "2983d45yun89q",
+		Alg: SHA256,
+	}
+	bc := NewBewitConfig(c, time.Hour)
+	bc.Ext = "some-app-data"
+	clock := &stubbedClock{}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if bc.GetBewit("http://example.com/resource/4?a=1&b=2", clock) == "" {
+			b.Fatal("empty bewit")
+		}
+	}
+}
@@ -0,0 +1,365 @@
+package hawk
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// bundleVersion is the version of the credential bundle format.
+const bundleVersion = 1
+
+// KEKProvider seals and opens the keys of a CredentialBundle with key-encryption keys (KEK) identified by id.
+// A provider backed by a KMS can seal and open the keys without the KEK ever leaving the KMS.
+type KEKProvider interface {
+	Seal(kekID string, plaintext, additionalData []byte) ([]byte, error)
+	Open(kekID string, ciphertext, additionalData []byte) ([]byte, error)
+}
+
+// LocalKEKProvider is a KEKProvider with local AES-256-GCM keys.
+// The sealed data is the random nonce followed by the ciphertext.
+// It is safe for concurrent use.
+type LocalKEKProvider struct {
+	mu    sync.RWMutex
+	aeads map[string]cipher.AEAD
+}
+
+// NewLocalKEKProvider initializes a new LocalKEKProvider.
+func NewLocalKEKProvider() *LocalKEKProvider {
+	return &LocalKEKProvider{aeads: make(map[string]cipher.AEAD)}
+}
+
+// Add adds the 32 bytes key with the id.
+func (p *LocalKEKProvider) Add(kekID string, key []byte) error {
+	if len(key) != 32 {
+		return errors.New("Invalid KEK length, 32 bytes are required.")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.aeads[kekID] = aead
+	return nil
+}
+
+func (p *LocalKEKProvider) aead(kekID string) (cipher.AEAD, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	aead, ok := p.aeads[kekID]
+	if !ok {
+		return nil, errors.New("Unknown KEK: " + kekID)
+	}
+	return aead, nil
+}
+
+func (p *LocalKEKProvider) Seal(kekID string, plaintext, additionalData []byte) ([]byte, error) {
+	aead, err := p.aead(kekID)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func (p *LocalKEKProvider) Open(kekID string, ciphertext, additionalData []byte) ([]byte, error) {
+	aead, err := p.aead(kekID)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errors.New("Invalid sealed key.")
+	}
+	n := aead.NonceSize()
+	return aead.Open(nil, ciphertext[:n], ciphertext[n:], additionalData)
+}
+
+// KEKFromEnv returns the KEK in the environment variable, encoded with base64.
+func KEKFromEnv(name string) ([]byte, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return nil, errors.New("Environment variable " + name + " is not set.")
+	}
+	return decodeKEK(v)
+}
+
+// KEKFromFile returns the KEK in the file, encoded with base64.
+func KEKFromFile(path string) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return decodeKEK(string(data))
+}
+
+func decodeKEK(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if key, err := base64.StdEncoding.DecodeString(s); err == nil {
+		return key, nil
+	}
+	key, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, errors.New("Invalid KEK encoding.")
+	}
+	return key, nil
+}
+
+// CredentialBundle is a set of credentials whose keys are sealed with a KEK.
+// Only the keys are sealed; the other fields of the entries are in clear, but authenticated:
+// a sealed key is bound to every field of its entry, so that an entry modified without the KEK,
+// e.g. to enable a disabled credential or to add scopes, can not be opened.
+//
+// A bundle is encoded as JSON, e.g.
+//
+//	{"version": 1, "kek_id": "kek-2020", "entries": [{"id": "partner-1", "alg": "sha256", "sealed_key": "..."}]}
+type CredentialBundle struct {
+	Version int           `json:"version"`
+	KEKID   string        `json:"kek_id"`
+	Entries []BundleEntry `json:"entries"`
+}
+
+// BundleEntry is a credential of a CredentialBundle. SealedKey is bound to all the other fields.
+type BundleEntry struct {
+	ID        string            `json:"id"`
+	Alg       Alg               `json:"alg"`
+	NotBefore *time.Time        `json:"not_before,omitempty"`
+	NotAfter  *time.Time        `json:"not_after,omitempty"`
+	Disabled  bool              `json:"disabled,omitempty"`
+	Scopes    []string          `json:"scopes,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	SealedKey []byte            `json:"sealed_key"`
+}
+
+// SealCredentials returns a new bundle of the credentials, with the keys sealed with the KEK.
+func SealCredentials(p KEKProvider, kekID string, creds ...*Credential) (*CredentialBundle, error) {
+	b := &CredentialBundle{Version: bundleVersion, KEKID: kekID}
+	for _, c := range creds {
+		if err := b.Add(p, c); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// Add seals the credential into the bundle. A credential with the same id is replaced.
+func (b *CredentialBundle) Add(p KEKProvider, cred *Credential) error {
+	if cred.ID == "" || cred.Key == "" {
+		return errors.New("Missing id or key.")
+	}
+	if cred.Alg != SHA256 && cred.Alg != SHA512 {
+		return errors.New("Invalid alg for " + cred.ID + ".")
+	}
+	c := cred.Clone()
+	e := BundleEntry{
+		ID:       c.ID,
+		Alg:      c.Alg,
+		Disabled: c.Disabled,
+		Scopes:   c.Scopes,
+		Metadata: c.Metadata,
+	}
+	if !c.NotBefore.IsZero() {
+		e.NotBefore = &c.NotBefore
+	}
+	if !c.NotAfter.IsZero() {
+		e.NotAfter = &c.NotAfter
+	}
+	sealed, err := p.Seal(b.KEKID, []byte(c.Key), e.additionalData())
+	if err != nil {
+		return err
+	}
+	e.SealedKey = sealed
+
+	for i := range b.Entries {
+		if b.Entries[i].ID == cred.ID {
+			b.Entries[i] = e
+			return nil
+		}
+	}
+	b.Entries = append(b.Entries, e)
+	return nil
+}
+
+// Remove removes the credential with the id from the bundle.
+func (b *CredentialBundle) Remove(id string) {
+	for i := range b.Entries {
+		if b.Entries[i].ID == id {
+			b.Entries = append(b.Entries[:i], b.Entries[i+1:]...)
+			return
+		}
+	}
+}
+
+// Rotate returns a new bundle with the keys sealed again with another KEK.
+// The provider must have both the current and the new KEK.
+func (b *CredentialBundle) Rotate(p KEKProvider, newKEKID string) (*CredentialBundle, error) {
+	rotated := &CredentialBundle{Version: bundleVersion, KEKID: newKEKID}
+	for _, e := range b.Entries {
+		ad := e.additionalData()
+		key, err := p.Open(b.KEKID, e.SealedKey, ad)
+		if err != nil {
+			return nil, errors.New("Failed to open the key of " + e.ID + ".")
+		}
+		sealed, err := p.Seal(newKEKID, key, ad)
+		if err != nil {
+			return nil, err
+		}
+		e.SealedKey = sealed
+		rotated.Entries = append(rotated.Entries, e)
+	}
+	return rotated, nil
+}
+
+// WriteFile writes the bundle to the file. The file is replaced atomically.
+func (b *CredentialBundle) WriteFile(path string) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// ReadCredentialBundle reads a bundle encoded as JSON.
+func ReadCredentialBundle(r io.Reader) (*CredentialBundle, error) {
+	var b CredentialBundle
+	if err := json.NewDecoder(r).Decode(&b); err != nil {
+		return nil, err
+	}
+	if b.Version != bundleVersion {
+		return nil, errors.New("Unsupported bundle version.")
+	}
+	return &b, nil
+}
+
+// ReadCredentialBundleFile reads a bundle from the file.
+func ReadCredentialBundleFile(path string) (*CredentialBundle, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadCredentialBundle(f)
+}
+
+// bundleAAD is the canonical encoding of the fields of a BundleEntry, but the sealed key.
+// Empty scopes and metadata are encoded as null, and the times in UTC, as they are encoded in the bundle.
+type bundleAAD struct {
+	ID        string            `json:"id"`
+	Alg       string            `json:"alg"`
+	NotBefore string            `json:"not_before"`
+	NotAfter  string            `json:"not_after"`
+	Disabled  bool              `json:"disabled"`
+	Scopes    []string          `json:"scopes"`
+	Metadata  map[string]string `json:"metadata"`
+}
+
+// additionalData returns the additional data of the sealed key, which binds it to the fields of the entry.
+func (e *BundleEntry) additionalData() []byte {
+	v := bundleAAD{ID: e.ID, Alg: e.Alg.String(), Disabled: e.Disabled}
+	if e.NotBefore != nil {
+		v.NotBefore = e.NotBefore.UTC().Format(time.RFC3339Nano)
+	}
+	if e.NotAfter != nil {
+		v.NotAfter = e.NotAfter.UTC().Format(time.RFC3339Nano)
+	}
+	if len(e.Scopes) > 0 {
+		v.Scopes = e.Scopes
+	}
+	if len(e.Metadata) > 0 {
+		v.Metadata = e.Metadata
+	}
+	// the encoding of a struct is deterministic, and the keys of a map are sorted.
+	// It does not fail, as all the fields are strings.
+	data, _ := json.Marshal(&v)
+	return append([]byte("hawk.bundle.1\n"), data...)
+}
+
+// BundleCredentialStore is a CredentialStore which reads the credentials of a CredentialBundle.
+// The key of a credential is opened on every GetCredential, and is not kept in the store.
+type BundleCredentialStore struct {
+	Provider KEKProvider
+
+	kekID   string
+	entries map[string]*BundleEntry
+}
+
+// NewBundleCredentialStore initializes a new BundleCredentialStore.
+func NewBundleCredentialStore(b *CredentialBundle, p KEKProvider) (*BundleCredentialStore, error) {
+	s := &BundleCredentialStore{
+		Provider: p,
+		kekID:    b.KEKID,
+		entries:  make(map[string]*BundleEntry, len(b.Entries)),
+	}
+	for i := range b.Entries {
+		e := &b.Entries[i]
+		if e.ID == "" || len(e.SealedKey) == 0 {
+			return nil, errors.New("Missing id or sealed key.")
+		}
+		if _, ok := s.entries[e.ID]; ok {
+			return nil, errors.New("Duplicate id " + e.ID + ".")
+		}
+		if e.Alg != SHA256 && e.Alg != SHA512 {
+			return nil, errors.New("Invalid alg for " + e.ID + ".")
+		}
+		s.entries[e.ID] = e
+	}
+	return s, nil
+}
+
+func (s *BundleCredentialStore) GetCredential(id string) (*Credential, error) {
+	e, ok := s.entries[id]
+	if !ok {
+		return nil, ErrCredentialNotFound
+	}
+	key, err := s.Provider.Open(s.kekID, e.SealedKey, e.additionalData())
+	if err != nil {
+		return nil, errors.New("Failed to open the key of " + id + ".")
+	}
+
+	c := &Credential{
+		ID:       e.ID,
+		Key:      string(key),
+		Alg:      e.Alg,
+		Disabled: e.Disabled,
+		Scopes:   e.Scopes,
+		Metadata: e.Metadata,
+	}
+	if e.NotBefore != nil {
+		c.NotBefore = *e.NotBefore
+	}
+	if e.NotAfter != nil {
+		c.NotAfter = *e.NotAfter
+	}
+	// the entry keeps its scopes and metadata.
+	return c.Clone(), nil
+}
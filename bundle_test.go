@@ -0,0 +1,203 @@
+package hawk
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testKEKProvider(t *testing.T, ids ...string) *LocalKEKProvider {
+	p := NewLocalKEKProvider()
+	for i, id := range ids {
+		if err := p.Add(id, bytes.Repeat([]byte{byte(i + 1)}, 32)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return p
+}
+
+func TestCredentialBundle(t *testing.T) {
+	p := testKEKProvider(t, "kek-1", "kek-2")
+	cred := &Credential{
+		ID:       "partner-1",
+		Key:      "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn",
+		Alg:      SHA256,
+		NotAfter: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+		Metadata: map[string]string{"tenant": "example"},
+	}
+	b, err := SealCredentials(p, "kek-1", cred, &Credential{ID: "partner-2", Key: "jg8U2oFrPbhn6ZG0JdgXUT2BnGyEUsNmSUbWIiA0lQQ", Alg: SHA512})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "bundle.json")
+	if err := b.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadFile(path)
+	if bytes.Contains(data, []byte(cred.Key)) {
+		t.Error("key is written in clear")
+	}
+
+	read, err := ReadCredentialBundleFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewBundleCredentialStore(read, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	act, err := s.GetCredential("partner-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if act.Key != cred.Key || act.Alg != SHA256 || !act.NotAfter.Equal(cred.NotAfter) || act.Metadata["tenant"] != "example" {
+		t.Errorf("unexpected credential: %v", act)
+	}
+	if _, err := s.GetCredential("unknown"); err == nil {
+		t.Error("expected an error for unknown id, but got nil")
+	}
+
+	// the server authenticates with the bundle
+	c := NewClient(cred, &Option{TimeStamp: time.Now().Unix(), Nonce: "3hOHpR"})
+	h, _ := c.Header("GET", "http://example.com/resource")
+	r, _ := http.NewRequest("GET", "http://example.com/resource", nil)
+	r.Header.Set("Authorization", h)
+	if _, err := NewServer(s).Authenticate(r); err != nil {
+		t.Errorf("return error, %s", err)
+	}
+
+	// rotation
+	rotated, err := read.Rotate(p, "kek-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated.Remove("partner-2")
+	s1, _ := NewBundleCredentialStore(rotated, testKEKProvider(t, "kek-0", "kek-2"))
+	if act, err := s1.GetCredential("partner-1"); err != nil || act.Key != cred.Key {
+		t.Errorf("unexpected credential: %v, %v", act, err)
+	}
+	if _, err := s1.GetCredential("partner-2"); err == nil {
+		t.Error("removed credential is returned")
+	}
+	if _, err := read.Rotate(testKEKProvider(t, "kek-0", "kek-2"), "kek-2"); err == nil {
+		t.Error("expected an error without the current kek, but got nil")
+	}
+
+	// a sealed key can not be moved to another id
+	read.Entries[1].SealedKey = read.Entries[0].SealedKey
+	s2, _ := NewBundleCredentialStore(read, p)
+	if _, err := s2.GetCredential("partner-2"); err == nil {
+		t.Error("expected an error for a swapped key, but got nil")
+	}
+}
+
+func TestCredentialBundle_Tampered(t *testing.T) {
+	p := testKEKProvider(t, "kek-1", "kek-2")
+	notAfter := time.Date(2030, 1, 1, 0, 0, 0, 0, time.FixedZone("JST", 9*60*60))
+	cred := &Credential{
+		ID:       "partner-1",
+		Key:      "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn",
+		Alg:      SHA256,
+		NotAfter: notAfter,
+		Disabled: true,
+		Scopes:   []string{"read"},
+		Metadata: map[string]string{"tenant": "example", "owner": "a"},
+	}
+	b, err := SealCredentials(p, "kek-1", cred)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the bundle is opened after it has been written and read again.
+	var buf bytes.Buffer
+	json.NewEncoder(&buf).Encode(b)
+	read, err := ReadCredentialBundle(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, _ := NewBundleCredentialStore(read, p)
+	if _, err := s.GetCredential("partner-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		tamper func(e *BundleEntry)
+	}{
+		{name: "enabled", tamper: func(e *BundleEntry) { e.Disabled = false }},
+		{name: "extended", tamper: func(e *BundleEntry) { n := notAfter.Add(time.Hour); e.NotAfter = &n }},
+		{name: "no expiry", tamper: func(e *BundleEntry) { e.NotAfter = nil }},
+		{name: "valid earlier", tamper: func(e *BundleEntry) { n := notAfter.Add(-time.Hour); e.NotBefore = &n }},
+		{name: "scope added", tamper: func(e *BundleEntry) { e.Scopes = append(e.Scopes, "admin") }},
+		{name: "metadata changed", tamper: func(e *BundleEntry) { e.Metadata = map[string]string{"tenant": "other", "owner": "a"} }},
+		{name: "alg changed", tamper: func(e *BundleEntry) { e.Alg = SHA512 }},
+	} {
+		tampered := *read
+		e := read.Entries[0]
+		e.Scopes = append([]string(nil), e.Scopes...)
+		tc.tamper(&e)
+		tampered.Entries = []BundleEntry{e}
+
+		s, _ := NewBundleCredentialStore(&tampered, p)
+		if _, err := s.GetCredential("partner-1"); err == nil {
+			t.Errorf("%s: expected an error, but got nil", tc.name)
+		}
+		if _, err := tampered.Rotate(p, "kek-2"); err == nil {
+			t.Errorf("%s: expected an error on rotation, but got nil", tc.name)
+		}
+	}
+}
+
+func TestReadCredentialBundle_Invalid(t *testing.T) {
+	for _, content := range []string{
+		`{"version": 2, "kek_id": "kek-1", "entries": []}`,
+		`{"version": 1`,
+	} {
+		if _, err := ReadCredentialBundle(strings.NewReader(content)); err == nil {
+			t.Errorf("expected an error for %s, but got nil", content)
+		}
+	}
+
+	p := testKEKProvider(t, "kek-1")
+	for _, b := range []*CredentialBundle{
+		{Version: 1, KEKID: "kek-1", Entries: []BundleEntry{{ID: "a", Alg: SHA256}}},
+		{Version: 1, KEKID: "kek-1", Entries: []BundleEntry{{ID: "a", SealedKey: []byte("x")}}},
+		{Version: 1, KEKID: "kek-1", Entries: []BundleEntry{{ID: "a", Alg: SHA256, SealedKey: []byte("x")}, {ID: "a", Alg: SHA256, SealedKey: []byte("x")}}},
+	} {
+		if _, err := NewBundleCredentialStore(b, p); err == nil {
+			t.Errorf("expected an error for %+v, but got nil", b)
+		}
+	}
+}
+
+func TestKEKFrom(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	encoded := base64.StdEncoding.EncodeToString(key)
+
+	os.Setenv("HAWK_TEST_KEK", encoded)
+	defer os.Unsetenv("HAWK_TEST_KEK")
+	if act, err := KEKFromEnv("HAWK_TEST_KEK"); err != nil || !bytes.Equal(act, key) {
+		t.Errorf("unexpected kek: %x, %v", act, err)
+	}
+	if _, err := KEKFromEnv("HAWK_TEST_KEK_MISSING"); err == nil {
+		t.Error("expected an error for missing variable, but got nil")
+	}
+
+	path := filepath.Join(t.TempDir(), "kek")
+	ioutil.WriteFile(path, []byte(base64.RawURLEncoding.EncodeToString(key)+"\n"), 0600)
+	if act, err := KEKFromFile(path); err != nil || !bytes.Equal(act, key) {
+		t.Errorf("unexpected kek: %x, %v", act, err)
+	}
+
+	if err := NewLocalKEKProvider().Add("short", key[:16]); err == nil {
+		t.Error("expected an error for short kek, but got nil")
+	}
+}
@@ -3,7 +3,9 @@ package hawk
 import (
 	"errors"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 )
 
 type Client struct {
@@ -42,32 +44,39 @@ func (c *Client) Header(method, uri string) (string, error) {
 		return "", err
 	}
 
-	header := "Hawk " +
-		`id="` + c.Credential.ID + `"` +
-		", " +
-		`ts="` + strconv.FormatInt(c.Option.TimeStamp, 10) + `"` +
-		", " +
-		`nonce="` + c.Option.Nonce + `"`
+	var b strings.Builder
+	b.Grow(160 + len(c.Option.Ext) + len(c.Option.App) + len(c.Option.Dlg))
+	b.WriteString("Hawk ")
+	writeHeaderAttr(&b, "id", c.Credential.ID)
+	writeHeaderAttr(&b, "ts", strconv.FormatInt(c.Option.TimeStamp, 10))
+	writeHeaderAttr(&b, "nonce", c.Option.Nonce)
 	if c.Option.Hash != "" {
-		header = header + ", " + `hash="` + c.Option.Hash + `"`
+		writeHeaderAttr(&b, "hash", c.Option.Hash)
 	}
 	if c.Option.Ext != "" {
-		header = header + ", " + `ext="` + c.Option.Ext + `"`
+		writeHeaderAttr(&b, "ext", c.Option.Ext)
 	}
-	header = header + ", " + `mac="` + mac + `"`
+	writeHeaderAttr(&b, "mac", mac)
 	if c.Option.App != "" {
-		header = header + ", " + `app="` + c.Option.App + `"`
+		writeHeaderAttr(&b, "app", c.Option.App)
 		if c.Option.Dlg != "" {
-			header = header + ", " + `dlg="` + c.Option.Dlg + `"`
+			writeHeaderAttr(&b, "dlg", c.Option.Dlg)
 		}
 	}
+	header := b.String()
 
 	return header, nil
 }
 
 // Authenticate authenticate the Hawk server response from the HTTP response.
 // Successful case returns true.
+// The Server-Authorization header is taken from the trailer if it is not in the header.
 func (c *Client) Authenticate(res *http.Response) (bool, error) {
+	return c.authenticate(res, res.Request.URL)
+}
+
+// authenticate authenticates the response to a request to u.
+func (c *Client) authenticate(res *http.Response, u *url.URL) (bool, error) {
 	artifacts := *c.Option
 
 	wah := res.Header.Get("WWW-Authenticate")
@@ -76,6 +85,10 @@ func (c *Client) Authenticate(res *http.Response) (bool, error) {
 	}
 
 	sah := res.Header.Get("Server-Authorization")
+	if sah == "" {
+		// a streamed response is signed in a trailer, available once the body has been read.
+		sah = res.Trailer.Get("Server-Authorization")
+	}
 	serverAuthAttributes := parseHawkHeader(sah)
 
 	artifacts.Ext = serverAuthAttributes["ext"]
@@ -84,16 +97,17 @@ func (c *Client) Authenticate(res *http.Response) (bool, error) {
 	m := &Mac{
 		Type:       Response,
 		Credential: c.Credential,
-		Uri:        res.Request.URL.String(),
+		Uri:        u.String(),
 		Method:     res.Request.Method,
 		Option:     &artifacts,
+		url:        u,
 	}
 
-	mac, err := m.String()
+	mac, err := m.digest()
 	if err != nil {
 		return false, err
 	}
-	if mac != serverAuthAttributes["mac"] {
+	if !macEqual(mac, serverAuthAttributes["mac"]) {
 		return false, errors.New("Bad response mac")
 	}
 
@@ -110,9 +124,75 @@ func (c *Client) Authenticate(res *http.Response) (bool, error) {
 		Payload:     c.Option.Payload,
 		Alg:         c.Credential.Alg,
 	}
-	if ph.String() != serverAuthAttributes["hash"] {
+	if !macEqual(ph.hash(), serverAuthAttributes["hash"]) {
 		return false, errors.New("Bad response payload mac")
 	}
 
 	return true, nil
 }
+
+// VerifyResponse verifies the Server-Authorization header of a response whose body has been read,
+// as Authenticate does, and the payload hash of the header against the body and the response Content-Type.
+// It reports whether the body is covered by the payload hash: a response signed without hash is verified,
+// but its body is not.
+func (c *Client) VerifyResponse(res *http.Response, body []byte) (bool, error) {
+	return c.verifyResponse(res, res.Request.URL, body)
+}
+
+// verifyResponse verifies the response to a request to u.
+func (c *Client) verifyResponse(res *http.Response, u *url.URL, body []byte) (bool, error) {
+	sah := res.Header.Get("Server-Authorization")
+	if sah == "" {
+		sah = res.Trailer.Get("Server-Authorization")
+	}
+	if sah == "" {
+		return false, errors.New("Missing Server-Authorization header.")
+	}
+
+	// the MAC covers the hash attribute, and the hash is checked here even if the body is empty.
+	artifacts := *c.Option
+	artifacts.Payload, artifacts.ContentType = "", ""
+	if ok, err := NewClient(c.Credential, &artifacts).authenticate(res, u); !ok {
+		return false, err
+	}
+
+	hash := parseHawkHeader(sah)["hash"]
+	if hash == "" {
+		return false, nil
+	}
+	ph := &PayloadHash{
+		ContentType: res.Header.Get("Content-Type"),
+		Payload:     string(body),
+		Alg:         c.Credential.Alg,
+	}
+	if !macEqual(ph.hash(), hash) {
+		return false, errors.New("Bad response payload mac")
+	}
+	return true, nil
+}
+
+// TimestampChallenge returns the server time of a stale timestamp challenge,
+// i.e. a WWW-Authenticate header such as `Hawk ts="1365711458", tsm="...", error="Stale timestamp"`.
+// The tsm attribute is verified with the credential, so that the client can retry the request
+// with a timestamp corrected by the difference with the server time.
+func (c *Client) TimestampChallenge(res *http.Response) (int64, error) {
+	attrs := parseHawkHeader(res.Header.Get("WWW-Authenticate"))
+	if attrs["ts"] == "" || attrs["tsm"] == "" {
+		return 0, errors.New("Missing timestamp challenge")
+	}
+
+	ts, err := strconv.ParseInt(attrs["ts"], 10, 64)
+	if err != nil {
+		return 0, errors.New("Invalid ts value.")
+	}
+
+	tm := &TsMac{TimeStamp: ts, Credential: c.Credential}
+	tsm, err := tm.digest()
+	if err != nil {
+		return 0, err
+	}
+	if !macEqual(tsm, attrs["tsm"]) {
+		return 0, errors.New("Bad timestamp mac")
+	}
+	return ts, nil
+}
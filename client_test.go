@@ -279,3 +279,58 @@ func TestClient_Authenticate_Fail(t *testing.T) {
 		t.Error("expected authenticate failed, but actual is successful.")
 	}
 }
+
+func TestClient_TimestampChallenge(t *testing.T) {
+	cred := &Credential{
+		ID:  "123456",
+		Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn",
+		Alg: SHA256,
+	}
+	c := NewClient(cred, &Option{TimeStamp: 1353832234, Nonce: "j4h3g2"})
+	tsm := (&TsMac{TimeStamp: 1365711458, Credential: cred}).String()
+
+	res := &http.Response{Header: http.Header{}}
+	res.Header.Set("WWW-Authenticate", `Hawk ts="1365711458", tsm="`+tsm+`", error="Stale timestamp"`)
+	ts, err := c.TimestampChallenge(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ts != 1365711458 {
+		t.Errorf("expected 1365711458, got %d", ts)
+	}
+
+	for _, v := range []string{
+		`Hawk`,
+		`Hawk ts="1365711458", error="Stale timestamp"`,
+		`Hawk ts="1365711459", tsm="` + tsm + `", error="Stale timestamp"`,
+		`Hawk ts="x", tsm="` + tsm + `"`,
+	} {
+		res.Header.Set("WWW-Authenticate", v)
+		if _, err := c.TimestampChallenge(res); err == nil {
+			t.Errorf("expected an error for %s", v)
+		}
+	}
+}
+
+func BenchmarkClient_Header(b *testing.B) {
+	c := NewClient(
+		&Credential{
+			ID:  "dh37fgj492je",
+			Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn",
+			Alg: SHA256,
+		},
+		&Option{
+			TimeStamp: int64(1353832234),
+			Nonce:     "j4h3g2",
+			Ext:       "some-app-ext-data",
+		},
+	)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := c.Header("GET", "http://example.com:8000/resource/1?b=1&a=2"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
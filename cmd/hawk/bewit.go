@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/url"
+	"time"
+
+	"github.com/hiyosi/hawk"
+)
+
+type bewitOutput struct {
+	URL   string `json:"url"`
+	Bewit string `json:"bewit"`
+	Exp   int64  `json:"exp"`
+}
+
+type nowClock int64
+
+func (c nowClock) Now(offset time.Duration) int64 {
+	return time.Unix(int64(c), 0).Add(offset).Unix()
+}
+
+func runBewit(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("bewit", stderr, "URL")
+	var (
+		cf  credentialFlags
+		out outputFlags
+	)
+	cf.register(fs)
+	out.register(fs)
+	ttl := fs.Duration("ttl", time.Hour, "lifetime of the bewit")
+	ext := fs.String("ext", "", "application specific data")
+	now := fs.Int64("now", 0, "time of issue in unix seconds (default now)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	if err := out.validate(); err != nil {
+		return fail(stderr, err)
+	}
+
+	cred, err := cf.credential()
+	if err != nil {
+		return fail(stderr, err)
+	}
+	u, err := url.Parse(fs.Arg(0))
+	if err != nil {
+		return fail(stderr, err)
+	}
+
+	b := hawk.NewBewitConfig(cred, *ttl)
+	b.Ext = *ext
+	var clock hawk.Clock
+	if *now != 0 {
+		clock = nowClock(*now)
+	}
+	bewit := b.GetBewit(fs.Arg(0), clock)
+	if bewit == "" {
+		return fail(stderr, errors.New("failed to build the bewit"))
+	}
+	parsed, err := hawk.ParseBewit(bewit)
+	if err != nil {
+		return fail(stderr, err)
+	}
+
+	if u.RawQuery == "" {
+		u.RawQuery = "bewit=" + bewit
+	} else {
+		u.RawQuery += "&bewit=" + bewit
+	}
+	if err := out.write(stdout, u.String(), &bewitOutput{URL: u.String(), Bewit: bewit, Exp: parsed.Exp}); err != nil {
+		return fail(stderr, err)
+	}
+	return 0
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/hiyosi/hawk"
+)
+
+// credentialFlags are the flags selecting the credential.
+type credentialFlags struct {
+	id, key, alg, file string
+}
+
+func (f *credentialFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.id, "id", "", "credential id (env HAWK_ID)")
+	fs.StringVar(&f.key, "key", "", "credential key (env HAWK_KEY)")
+	fs.StringVar(&f.alg, "alg", "", "credential algorithm, sha256 or sha512 (env HAWK_ALG, default sha256)")
+	fs.StringVar(&f.file, "credentials", "", "JSON file of a credential or an array of credentials, selected by -id (env HAWK_CREDENTIALS)")
+}
+
+// credential returns the credential given by the flags, the environment variables or the file, in this order.
+func (f *credentialFlags) credential() (*hawk.Credential, error) {
+	id := first(f.id, os.Getenv("HAWK_ID"))
+	key := first(f.key, os.Getenv("HAWK_KEY"))
+	alg := first(f.alg, os.Getenv("HAWK_ALG"))
+
+	cred := &hawk.Credential{ID: id, Key: key}
+	if file := first(f.file, os.Getenv("HAWK_CREDENTIALS")); file != "" && (id == "" || key == "") {
+		c, err := readCredential(file, id)
+		if err != nil {
+			return nil, err
+		}
+		cred = c
+	}
+
+	if alg != "" {
+		if err := cred.Alg.UnmarshalText([]byte(alg)); err != nil {
+			return nil, err
+		}
+	}
+	if cred.Alg == 0 {
+		cred.Alg = hawk.SHA256
+	}
+	if cred.ID == "" || cred.Key == "" {
+		return nil, errors.New("credential id and key are required, see -id, -key and -credentials")
+	}
+	return cred, nil
+}
+
+// readCredential reads a credential from a JSON file of a credential or an array of credentials.
+func readCredential(path, id string) (*hawk.Credential, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var creds []*hawk.Credential
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		err = json.Unmarshal(data, &creds)
+	} else {
+		var c hawk.Credential
+		err = json.Unmarshal(data, &c)
+		creds = append(creds, &c)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid credential file %s: %s", path, err)
+	}
+
+	for _, c := range creds {
+		if id == "" && len(creds) == 1 || c.ID == id {
+			return c, nil
+		}
+	}
+	if id == "" {
+		return nil, fmt.Errorf("%s has several credentials, select one with -id", path)
+	}
+	return nil, fmt.Errorf("credential %s not found in %s", id, path)
+}
+
+// payloadFlags are the flags of the request body.
+type payloadFlags struct {
+	data, dataFile, contentType string
+}
+
+func (f *payloadFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.data, "data", "", "request body, or @file to read it from a file, @- for stdin")
+	fs.StringVar(&f.dataFile, "data-file", "", "file of the request body, - for stdin")
+	fs.StringVar(&f.contentType, "content-type", "", "content type of the body, which is then covered by the payload hash")
+}
+
+// payload returns the body, and whether it is covered by the payload hash.
+func (f *payloadFlags) payload() (string, bool, error) {
+	body, file := f.data, f.dataFile
+	if strings.HasPrefix(body, "@") {
+		file = body[1:]
+	}
+	if file != "" {
+		var data []byte
+		var err error
+		if file == "-" {
+			data, err = ioutil.ReadAll(os.Stdin)
+		} else {
+			data, err = ioutil.ReadFile(file)
+		}
+		if err != nil {
+			return "", false, err
+		}
+		body = string(data)
+	}
+	return body, f.contentType != "", nil
+}
+
+// outputFlags are the flags of the output format.
+type outputFlags struct {
+	format string
+}
+
+func (f *outputFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.format, "format", "text", "output format, text or json")
+}
+
+func (f *outputFlags) validate() error {
+	if f.format != "text" && f.format != "json" {
+		return fmt.Errorf("invalid format %q", f.format)
+	}
+	return nil
+}
+
+// write prints the text, or v as JSON.
+func (f *outputFlags) write(w io.Writer, text string, v interface{}) error {
+	if f.format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		return enc.Encode(v)
+	}
+	_, err := fmt.Fprintln(w, text)
+	return err
+}
+
+func newFlagSet(name string, stderr io.Writer, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: hawk %s [flags] %s\n\nFlags:\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func first(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func fail(stderr io.Writer, err error) int {
+	fmt.Fprintln(stderr, "hawk:", err)
+	return 1
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/hiyosi/hawk"
+)
+
+// readHosts reads the credentials by host from a JSON object, e.g.
+//
+//	{"api.example.com": {"id": "dh37fgj492je", "key": "...", "alg": "sha256"}}
+func readHosts(path string) (map[string]*hawk.Credential, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var hosts map[string]*hawk.Credential
+	if err := json.Unmarshal(data, &hosts); err != nil {
+		return nil, fmt.Errorf("invalid hosts file %s: %s", path, err)
+	}
+	for host, c := range hosts {
+		if c == nil || c.ID == "" || c.Key == "" {
+			return nil, fmt.Errorf("invalid credential for %s in %s", host, path)
+		}
+		if c.Alg == 0 {
+			c.Alg = hawk.SHA256
+		}
+	}
+	return hosts, nil
+}
+
+func runForwardProxy(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("forward-proxy", stderr, "")
+	listen := fs.String("listen", "localhost:8081", "address to listen on")
+	hostsFile := fs.String("hosts", "", `JSON file of the credentials by host, e.g. {"api.example.com": {"id": ..., "key": ..., "alg": "sha256"}}`)
+	https := fs.Bool("https", false, "forward the requests to the upstream hosts with HTTPS")
+	noVerify := fs.Bool("no-verify", false, "do not verify the Server-Authorization header of the responses")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return 2
+	}
+	if *hostsFile == "" {
+		return fail(stderr, errors.New("-hosts is required"))
+	}
+
+	hosts, err := readHosts(*hostsFile)
+	if err != nil {
+		return fail(stderr, err)
+	}
+	p := hawk.NewSigningProxy(hosts)
+	p.UpgradeToHTTPS = *https
+	p.Transport.SkipResponseVerification = *noVerify
+
+	fmt.Fprintf(stderr, "hawk: signing the requests to %d hosts, set HTTP_PROXY=http://%s\n", len(hosts), *listen)
+	return fail(stderr, http.ListenAndServe(*listen, p))
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"io"
+	"strings"
+
+	"github.com/hiyosi/hawk"
+)
+
+func runKeygen(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("keygen", stderr, "")
+	var out outputFlags
+	out.register(fs)
+	alg := fs.String("alg", "sha256", "algorithm, sha256 or sha512")
+	prefix := fs.String("id-prefix", "", "prefix of the generated ids")
+	idLength := fs.Int("id-length", 8, "number of random bytes of the ids, hex encoded")
+	keyLength := fs.Int("key-length", 0, "number of random bytes of the keys, base64url encoded (default the hash size of the algorithm)")
+	count := fs.Int("n", 1, "number of credentials")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return 2
+	}
+	if err := out.validate(); err != nil {
+		return fail(stderr, err)
+	}
+
+	g := &hawk.CredentialGenerator{IDPrefix: *prefix, IDLength: *idLength, KeyLength: *keyLength}
+	if err := g.Alg.UnmarshalText([]byte(*alg)); err != nil {
+		return fail(stderr, err)
+	}
+
+	creds := make([]*hawk.Credential, 0, *count)
+	var text strings.Builder
+	for i := 0; i < *count; i++ {
+		c, err := g.Generate()
+		if err != nil {
+			return fail(stderr, err)
+		}
+		creds = append(creds, c)
+
+		if i > 0 {
+			text.WriteString("\n\n")
+		}
+		a, _ := c.Alg.MarshalText()
+		text.WriteString("id: " + c.ID + "\nkey: " + c.Key + "\nalgorithm: " + string(a))
+	}
+
+	// the JSON output can be loaded by hawk.FileCredentialStore.
+	if err := out.write(stdout, text.String(), creds); err != nil {
+		return fail(stderr, err)
+	}
+	return 0
+}
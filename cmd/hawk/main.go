@@ -0,0 +1,76 @@
+// Command hawk signs and verifies Hawk requests, e.g. to debug an integration.
+// The request command sends a signed request and verifies the response, like curl.
+// The proxy command runs a reverse proxy which authenticates the requests to a service without authentication,
+// and the forward-proxy command a local proxy which signs the requests of clients which cannot compute Hawk headers.
+//
+// Usage:
+//
+//	hawk sign [flags] METHOD URL
+//	hawk bewit [flags] URL
+//	hawk verify [flags] METHOD URL
+//	hawk keygen [flags]
+//	hawk request [flags] URL
+//	hawk proxy [flags]
+//	hawk forward-proxy [flags]
+//
+// The credential is taken from the -id, -key and -alg flags, from the HAWK_ID, HAWK_KEY and HAWK_ALG
+// environment variables, or from a JSON file given by -credentials or HAWK_CREDENTIALS, in this order.
+// Every command prints text, or JSON with -format json.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+type command struct {
+	usage string
+	run   func(args []string, stdout, stderr io.Writer) int
+}
+
+var commands = map[string]command{
+	"sign":          {usage: "sign [flags] METHOD URL: print an Authorization header", run: runSign},
+	"bewit":         {usage: "bewit [flags] URL: print a URL with a bewit parameter", run: runBewit},
+	"verify":        {usage: "verify [flags] METHOD URL: verify an Authorization header or a bewit", run: runVerify},
+	"keygen":        {usage: "keygen [flags]: generate credentials", run: runKeygen},
+	"forward-proxy": {usage: "forward-proxy [flags]: run a local proxy which signs the requests", run: runForwardProxy},
+	"proxy":         {usage: "proxy [flags]: run an authenticating reverse proxy", run: runProxy},
+	"request":       {usage: "request [flags] URL: send a signed request and verify the response", run: runRequest},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return 2
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		if args[0] != "help" && args[0] != "-h" && args[0] != "-help" {
+			fmt.Fprintf(stderr, "hawk: unknown command %q\n", args[0])
+		}
+		usage(stderr)
+		return 2
+	}
+	return cmd.run(args[1:], stdout, stderr)
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: hawk COMMAND [flags] ARGS")
+	fmt.Fprintln(w)
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintln(w, "  "+commands[name].usage)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'hawk COMMAND -h' for the flags of a command.")
+}
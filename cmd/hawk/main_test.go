@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hiyosi/hawk"
+)
+
+var testCredentialArgs = []string{"-id", "dh37fgj492je", "-key", "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn", "-alg", "sha256"}
+
+func runCommand(t *testing.T, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func commandArgs(name string, flags []string, args ...string) []string {
+	a := append([]string{name}, testCredentialArgs...)
+	a = append(a, flags...)
+	return append(a, args...)
+}
+
+func TestRun_Usage(t *testing.T) {
+	code, _, stderr := runCommand(t)
+	if code != 2 || !strings.Contains(stderr, "keygen") {
+		t.Errorf("unexpected result: %d %q", code, stderr)
+	}
+
+	code, _, stderr = runCommand(t, "unknown")
+	if code != 2 || !strings.Contains(stderr, `unknown command "unknown"`) {
+		t.Errorf("unexpected result: %d %q", code, stderr)
+	}
+
+	code, _, _ = runCommand(t, "sign", "GET")
+	if code != 2 {
+		t.Errorf("expected usage error, got %d", code)
+	}
+}
+
+func TestSign(t *testing.T) {
+	code, stdout, stderr := runCommand(t, commandArgs("sign",
+		[]string{"-ts", "1353832234", "-nonce", "j4h3g2", "-ext", "some-app-ext-data"},
+		"get", "http://example.com:8000/resource/1?b=1&a=2")...)
+	if code != 0 {
+		t.Fatalf("unexpected exit code %d: %s", code, stderr)
+	}
+
+	expect := `Hawk id="dh37fgj492je", ts="1353832234", nonce="j4h3g2", ext="some-app-ext-data", mac="6R4rV5iE+NPoym+WwjeHzjAGXUtLNIxmo1vpMofpLAE="`
+	if strings.TrimSpace(stdout) != expect {
+		t.Errorf("expected %s, got %s", expect, stdout)
+	}
+}
+
+func TestSign_JSON(t *testing.T) {
+	code, stdout, stderr := runCommand(t, commandArgs("sign",
+		[]string{"-ts", "1353832234", "-nonce", "j4h3g2", "-ext", "some-app-ext-data", "-data", "Thank you for flying Hawk", "-content-type", "text/plain", "-format", "json"},
+		"POST", "http://example.com:8000/resource/1?b=1&a=2")...)
+	if code != 0 {
+		t.Fatalf("unexpected exit code %d: %s", code, stderr)
+	}
+
+	var out signOutput
+	if err := json.Unmarshal([]byte(stdout), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Hash != "Yi9LfIIFRtBEPt74PVmbTF/xVAwPn7ub15ePICfgnuY=" {
+		t.Errorf("unexpected hash %s", out.Hash)
+	}
+	if !strings.HasSuffix(out.Authorization, `mac="aSe1DERmZuRl3pI36/9BdZmnErTw3sNzOOAUlfeKjVw="`) {
+		t.Errorf("unexpected header %s", out.Authorization)
+	}
+	if !strings.HasPrefix(out.Normalized, "hawk.1.header\n1353832234\nj4h3g2\nPOST\n/resource/1?b=1&a=2\nexample.com\n8000\n") {
+		t.Errorf("unexpected normalized string %q", out.Normalized)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	url := "http://example.com:8000/resource/1?b=1&a=2"
+	payload := []string{"-data", "Thank you for flying Hawk", "-content-type", "text/plain"}
+	_, header, _ := runCommand(t, commandArgs("sign", append([]string{"-ts", "1353832234"}, payload...), "POST", url)...)
+	header = strings.TrimSpace(header)
+
+	code, stdout, stderr := runCommand(t, commandArgs("verify",
+		append([]string{"-header", header, "-now", "1353832240"}, payload...), "POST", url)...)
+	if code != 0 {
+		t.Fatalf("unexpected exit code %d: %s %s", code, stdout, stderr)
+	}
+	if !strings.Contains(stdout, "OK: header authenticated, id=dh37fgj492je") || !strings.Contains(stdout, "clock skew: -6s") {
+		t.Errorf("unexpected output %s", stdout)
+	}
+
+	tests := []struct {
+		name   string
+		args   []string
+		method string
+		reason hawk.FailureReason
+	}{
+		{"stale timestamp", append([]string{"-header", header, "-now", "1353835000"}, payload...), "POST", hawk.ReasonStaleTimestamp},
+		{"method", append([]string{"-header", header, "-now", "1353832240"}, payload...), "PUT", hawk.ReasonBadMac},
+		{"body", []string{"-header", header, "-now", "1353832240", "-data", "tampered", "-content-type", "text/plain"}, "POST", hawk.ReasonBadHash},
+		{"unknown id", []string{"-header", strings.Replace(header, `id="dh37fgj492je"`, `id="unknown"`, 1)}, "POST", hawk.ReasonUnknownCredential},
+	}
+	for _, tt := range tests {
+		code, stdout, stderr := runCommand(t, commandArgs("verify", append(tt.args, "-format", "json"), tt.method, url)...)
+		if code != 1 {
+			t.Errorf("%s: unexpected exit code %d: %s", tt.name, code, stderr)
+			continue
+		}
+		var out verifyOutput
+		if err := json.Unmarshal([]byte(stdout), &out); err != nil {
+			t.Fatal(err)
+		}
+		if out.OK || out.Reason != string(tt.reason) {
+			t.Errorf("%s: expected %s, got %+v", tt.name, tt.reason, out)
+		}
+	}
+}
+
+func TestVerify_BadMac(t *testing.T) {
+	header := `Hawk id="dh37fgj492je", ts="1353832234", nonce="j4h3g2", mac="6R4rV5iE+NPoym+WwjeHzjAGXUtLNIxmo1vpMofpLAE="`
+	code, stdout, _ := runCommand(t, commandArgs("verify",
+		[]string{"-header", header, "-now", "1353832234"}, "GET", "http://example.com:8000/resource/1?b=1&a=2")...)
+	if code != 1 {
+		t.Fatalf("unexpected exit code %d", code)
+	}
+
+	for _, s := range []string{
+		"FAIL: bad_mac: Bad MAC",
+		"supplied mac: 6R4rV5iE+NPoym+WwjeHzjAGXUtLNIxmo1vpMofpLAE=",
+		"expected mac: ",
+		"normalized string:\n  hawk.1.header\n  1353832234\n  j4h3g2\n  GET\n",
+	} {
+		if !strings.Contains(stdout, s) {
+			t.Errorf("expected %q in %s", s, stdout)
+		}
+	}
+}
+
+func TestBewit_Verify(t *testing.T) {
+	code, stdout, stderr := runCommand(t, commandArgs("bewit",
+		[]string{"-now", "1365711458", "-ttl", "1m", "-format", "json"}, "https://example.com/somewhere/over/the/rainbow?a=1")...)
+	if code != 0 {
+		t.Fatalf("unexpected exit code %d: %s", code, stderr)
+	}
+	var out bewitOutput
+	if err := json.Unmarshal([]byte(stdout), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Exp != 1365711518 || !strings.Contains(out.URL, "?a=1&bewit=") {
+		t.Errorf("unexpected output %+v", out)
+	}
+
+	code, stdout, _ = runCommand(t, commandArgs("verify", []string{"-now", "1365711460"}, "GET", out.URL)...)
+	if code != 0 || !strings.HasPrefix(stdout, "OK: bewit authenticated, id=dh37fgj492je") {
+		t.Errorf("unexpected result %d %s", code, stdout)
+	}
+
+	code, stdout, _ = runCommand(t, commandArgs("verify", []string{"-now", "1365711600"}, "GET", out.URL)...)
+	if code != 1 || !strings.HasPrefix(stdout, "FAIL: expired") {
+		t.Errorf("unexpected result %d %s", code, stdout)
+	}
+}
+
+func TestKeygen(t *testing.T) {
+	code, stdout, stderr := runCommand(t, "keygen", "-alg", "sha512", "-id-prefix", "partner-", "-n", "2", "-format", "json")
+	if code != 0 {
+		t.Fatalf("unexpected exit code %d: %s", code, stderr)
+	}
+
+	var creds []*hawk.Credential
+	if err := json.Unmarshal([]byte(stdout), &creds); err != nil {
+		t.Fatal(err)
+	}
+	if len(creds) != 2 || creds[0].ID == creds[1].ID {
+		t.Fatalf("unexpected credentials %v", creds)
+	}
+	for _, c := range creds {
+		if !strings.HasPrefix(c.ID, "partner-") || c.Alg != hawk.SHA512 {
+			t.Errorf("unexpected credential %v", c)
+		}
+		if err := hawk.DefaultKeyPolicy.Validate(c); err != nil {
+			t.Error(err)
+		}
+	}
+
+	code, _, stderr = runCommand(t, "keygen", "-alg", "md5")
+	if code != 1 || !strings.Contains(stderr, "hawk:") {
+		t.Errorf("unexpected result %d %q", code, stderr)
+	}
+}
+
+func TestCredentialFlags_Env(t *testing.T) {
+	t.Setenv("HAWK_ID", "dh37fgj492je")
+	t.Setenv("HAWK_KEY", "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn")
+
+	code, stdout, stderr := runCommand(t, "sign", "-ts", "1353832234", "-nonce", "j4h3g2", "-ext", "some-app-ext-data",
+		"GET", "http://example.com:8000/resource/1?b=1&a=2")
+	if code != 0 || !strings.Contains(stdout, `mac="6R4rV5iE+NPoym+WwjeHzjAGXUtLNIxmo1vpMofpLAE="`) {
+		t.Errorf("unexpected result %d %s %s", code, stdout, stderr)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/hiyosi/hawk"
+)
+
+// proxyFlags are the flags of the authenticating reverse proxy.
+type proxyFlags struct {
+	upstream      string
+	credentials   string
+	allowBewit    bool
+	signResponses bool
+	maxBody       int64
+	skew          time.Duration
+	headers       hawk.IdentityHeaders
+}
+
+func (f *proxyFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.upstream, "upstream", "", "URL of the upstream service")
+	fs.StringVar(&f.credentials, "credentials", "", "JSON file of the accepted credentials (env HAWK_CREDENTIALS)")
+	fs.BoolVar(&f.allowBewit, "allow-bewit", false, "accept bewits for GET and HEAD requests")
+	fs.BoolVar(&f.signResponses, "sign-responses", false, "sign the responses with a Server-Authorization header")
+	fs.Int64Var(&f.maxBody, "max-body", hawk.DefaultMaxBodySize, "maximum size of a request body, which is verified against the payload hash before it is forwarded")
+	fs.DurationVar(&f.skew, "skew", 60*time.Second, "allowed clock skew")
+	d := hawk.DefaultIdentityHeaders
+	fs.StringVar(&f.headers.ID, "id-header", d.ID, "header of the credential id forwarded to the upstream, empty to disable")
+	fs.StringVar(&f.headers.Ext, "ext-header", d.Ext, "header of the ext attribute forwarded to the upstream, empty to disable")
+	fs.StringVar(&f.headers.App, "app-header", d.App, "header of the app attribute forwarded to the upstream, empty to disable")
+	fs.StringVar(&f.headers.Dlg, "dlg-header", d.Dlg, "header of the dlg attribute forwarded to the upstream, empty to disable")
+	fs.StringVar(&f.headers.Scopes, "scopes-header", d.Scopes, "header of the credential scopes forwarded to the upstream, empty to disable")
+}
+
+// reverseProxy returns the reverse proxy, and the store of the credentials file.
+func (f *proxyFlags) reverseProxy() (*hawk.ReverseProxy, *hawk.FileCredentialStore, error) {
+	if f.upstream == "" {
+		return nil, nil, errors.New("-upstream is required")
+	}
+	target, err := url.Parse(f.upstream)
+	if err != nil {
+		return nil, nil, err
+	}
+	path := first(f.credentials, os.Getenv("HAWK_CREDENTIALS"))
+	if path == "" {
+		return nil, nil, errors.New("-credentials is required")
+	}
+	store, err := hawk.NewFileCredentialStore(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s := hawk.NewServer(store)
+	s.TimeStampSkew = f.skew
+	s.NonceValidator = newNonceCache(2 * f.skew)
+
+	rules := []hawk.Rule{{}}
+	if f.allowBewit {
+		rules = []hawk.Rule{{Methods: []string{"GET", "HEAD"}, AllowBewit: true}, {}}
+	}
+	p := hawk.NewReverseProxy(s, target, rules...)
+	p.IdentityHeaders = f.headers
+	p.Authorizer.MaxBodySize = f.maxBody
+	p.SignResponses = f.signResponses
+	return p, store, nil
+}
+
+func runProxy(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("proxy", stderr, "")
+	var pf proxyFlags
+	pf.register(fs)
+	listen := fs.String("listen", "localhost:8080", "address to listen on")
+	watch := fs.Duration("watch", 0, "interval to check the credentials file for changes, 0 to reload it on SIGHUP only")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return 2
+	}
+
+	p, store, err := pf.reverseProxy()
+	if err != nil {
+		return fail(stderr, err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go store.ReloadOnSignal(ctx, syscall.SIGHUP)
+	if *watch > 0 {
+		go store.Watch(ctx, *watch)
+	}
+
+	fmt.Fprintf(stderr, "hawk: proxying %s to %s with %d credentials\n", *listen, pf.upstream, store.Len())
+	return fail(stderr, http.ListenAndServe(*listen, p))
+}
+
+// nonceCache is a hawk.NonceValidator which remembers the nonces for ttl.
+type nonceCache struct {
+	ttl time.Duration
+
+	mu        sync.Mutex
+	seen      map[string]time.Time
+	lastSweep time.Time
+}
+
+func newNonceCache(ttl time.Duration) *nonceCache {
+	return &nonceCache{ttl: ttl, seen: make(map[string]time.Time)}
+}
+
+func (c *nonceCache) Validate(key, nonce string, ts int64) bool {
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if now.Sub(c.lastSweep) > c.ttl {
+		for k, t := range c.seen {
+			if now.Sub(t) > c.ttl {
+				delete(c.seen, k)
+			}
+		}
+		c.lastSweep = now
+	}
+
+	k := key + "\x00" + nonce
+	if _, ok := c.seen[k]; ok {
+		return false
+	}
+	c.seen[k] = now
+	return true
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hiyosi/hawk"
+)
+
+func TestProxy(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "credentials.json")
+	creds := `[{"id": "dh37fgj492je", "key": "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn", "alg": "sha256", "scopes": ["read"]}]`
+	if err := ioutil.WriteFile(file, []byte(creds), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var upstreamHeader http.Header
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamHeader = r.Header
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprint(w, "hello")
+	}))
+	defer upstream.Close()
+
+	var pf proxyFlags
+	fs := flag.NewFlagSet("proxy", flag.ContinueOnError)
+	pf.register(fs)
+	if err := fs.Parse([]string{"-upstream", upstream.URL, "-credentials", file, "-sign-responses", "-ext-header", ""}); err != nil {
+		t.Fatal(err)
+	}
+	p, _, err := pf.reverseProxy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(p)
+	defer ts.Close()
+
+	code, stdout, stderr := runCommand(t, commandArgs("request", []string{"-ext", "some-app-ext-data"}, ts.URL+"/resource")...)
+	if code != 0 || stdout != "hello" || !strings.Contains(stderr, "hawk: response verified\n") {
+		t.Fatalf("unexpected result %d %q %q", code, stdout, stderr)
+	}
+	if upstreamHeader.Get("Authorization") != "" || upstreamHeader.Get("X-Hawk-Id") != "dh37fgj492je" ||
+		upstreamHeader.Get("X-Hawk-Scopes") != "read" || upstreamHeader.Get("X-Hawk-Ext") != "" {
+		t.Errorf("unexpected upstream headers %v", upstreamHeader)
+	}
+
+	// a replayed request is rejected
+	_, header, _ := runCommand(t, commandArgs("sign", nil, "GET", ts.URL+"/resource")...)
+	for i, expect := range []int{http.StatusOK, http.StatusUnauthorized} {
+		req, _ := http.NewRequest("GET", ts.URL+"/resource", nil)
+		req.Header.Set("Authorization", strings.TrimSpace(header))
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != expect {
+			t.Errorf("request %d: expected %d, got %d", i, expect, res.StatusCode)
+		}
+	}
+}
+
+func TestNonceCache(t *testing.T) {
+	c := newNonceCache(time.Minute)
+	if !c.Validate("id", "n1", 0) || !c.Validate("id", "n2", 0) || !c.Validate("other", "n1", 0) {
+		t.Error("expected new nonces to be accepted")
+	}
+	if c.Validate("id", "n1", 0) {
+		t.Error("expected a replayed nonce to be rejected")
+	}
+}
+
+func TestReadHosts(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "hosts.json")
+	if err := ioutil.WriteFile(file, []byte(`{"api.example.com": {"id": "dh37fgj492je", "key": "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn"}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	hosts, err := readHosts(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := hosts["api.example.com"]; c == nil || c.ID != "dh37fgj492je" || c.Alg != hawk.SHA256 {
+		t.Errorf("unexpected hosts %v", hosts)
+	}
+
+	if err := ioutil.WriteFile(file, []byte(`{"api.example.com": {"id": "dh37fgj492je"}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readHosts(file); err == nil {
+		t.Error("expected an error for a credential without key")
+	}
+}
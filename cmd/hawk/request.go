@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hiyosi/hawk"
+)
+
+type requestOutput struct {
+	Status          int         `json:"status"`
+	Proto           string      `json:"proto"`
+	Header          http.Header `json:"header"`
+	Body            string      `json:"body"`
+	Verified        bool        `json:"verified"`
+	PayloadVerified bool        `json:"payload_verified"`
+	Error           string      `json:"error,omitempty"`
+	ClockSkew       *int64      `json:"clock_skew,omitempty"`
+	Attempts        int         `json:"attempts"`
+}
+
+// headerFlag is a repeatable flag of request headers.
+type headerFlag http.Header
+
+func (h headerFlag) String() string {
+	return ""
+}
+
+func (h headerFlag) Set(v string) error {
+	i := strings.IndexByte(v, ':')
+	if i <= 0 {
+		return fmt.Errorf("invalid header %q", v)
+	}
+	http.Header(h).Add(strings.TrimSpace(v[:i]), strings.TrimSpace(v[i+1:]))
+	return nil
+}
+
+func runRequest(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("request", stderr, "URL")
+	var (
+		cf  credentialFlags
+		pf  payloadFlags
+		sf  signFlags
+		out outputFlags
+	)
+	cf.register(fs)
+	pf.register(fs)
+	sf.register(fs)
+	out.register(fs)
+	method := fs.String("X", "", "request method (default GET, or POST with a body)")
+	headers := headerFlag{}
+	fs.Var(headers, "H", "request header, e.g. 'Accept: application/json', may be repeated")
+	include := fs.Bool("i", false, "print the status line and the headers of the response")
+	retries := fs.Int("retries", 1, "number of retries after a stale timestamp challenge")
+	timeout := fs.Duration("timeout", 30*time.Second, "request timeout")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	if err := out.validate(); err != nil {
+		return fail(stderr, err)
+	}
+	rawurl := fs.Arg(0)
+
+	cred, err := cf.credential()
+	if err != nil {
+		return fail(stderr, err)
+	}
+	body, _, err := pf.payload()
+	if err != nil {
+		return fail(stderr, err)
+	}
+	m := strings.ToUpper(*method)
+	if m == "" {
+		m = "GET"
+		if body != "" {
+			m = "POST"
+		}
+	}
+	opt, err := sf.option(cred, pf.contentType, body)
+	if err != nil {
+		return fail(stderr, err)
+	}
+
+	client := &http.Client{
+		Timeout: *timeout,
+		// a redirect is not followed, as the MAC covers the original URL.
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	res := &requestOutput{}
+	var resp *http.Response
+	var respBody []byte
+	for {
+		res.Attempts++
+		resp, respBody, err = send(client, cred, opt, m, rawurl, body, pf.contentType, http.Header(headers))
+		if err != nil {
+			return fail(stderr, err)
+		}
+		if resp.StatusCode != http.StatusUnauthorized || res.Attempts > *retries {
+			break
+		}
+
+		ts, err := hawk.NewClient(cred, opt).TimestampChallenge(resp)
+		if err != nil {
+			// not a stale timestamp challenge
+			break
+		}
+		now := time.Now().Unix()
+		skew := now - ts
+		res.ClockSkew = &skew
+		if out.format == "text" {
+			fmt.Fprintf(stderr, "hawk: stale timestamp, clock skew %ds (client - server), retrying\n", skew)
+		}
+		opt.TimeStamp = ts
+		if opt.Nonce, err = hawk.Nonce(6); err != nil {
+			return fail(stderr, err)
+		}
+	}
+
+	res.Status = resp.StatusCode
+	res.Proto = resp.Proto
+	res.Header = resp.Header
+	res.Body = string(respBody)
+	res.PayloadVerified, err = hawk.NewClient(cred, opt).VerifyResponse(resp, respBody)
+	res.Verified = err == nil
+	if err != nil {
+		res.Error = err.Error()
+	}
+
+	if out.format == "json" {
+		if err := out.write(stdout, "", res); err != nil {
+			return fail(stderr, err)
+		}
+	} else {
+		if *include {
+			fmt.Fprintf(stdout, "%s %s\r\n", resp.Proto, resp.Status)
+			resp.Header.Write(stdout)
+			fmt.Fprint(stdout, "\r\n")
+		}
+		stdout.Write(respBody)
+		fmt.Fprintln(stderr, "hawk:", res.verdict())
+	}
+
+	if !res.Verified {
+		return 1
+	}
+	return 0
+}
+
+// send signs and sends the request, and returns the response with its body.
+func send(client *http.Client, cred *hawk.Credential, opt *hawk.Option, method, rawurl, body, contentType string, headers http.Header) (*http.Response, []byte, error) {
+	req, err := http.NewRequest(method, rawurl, strings.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	for k, v := range headers {
+		req.Header[k] = v
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	h, err := hawk.NewClient(cred, opt).Header(method, rawurl)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Authorization", h)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, data, nil
+}
+
+func (r *requestOutput) verdict() string {
+	switch {
+	case !r.Verified:
+		return "response not verified: " + r.Error
+	case r.PayloadVerified:
+		return "response verified"
+	default:
+		return "response verified, the body is not covered by a payload hash"
+	}
+}
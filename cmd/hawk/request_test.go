@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hiyosi/hawk"
+)
+
+// newHawkServer returns a server which is one hour ahead, answers a stale timestamp with a challenge,
+// and signs its responses.
+func newHawkServer(respond func(w http.ResponseWriter, r *http.Request, cred *hawk.Credential)) *httptest.Server {
+	cred := &hawk.Credential{ID: "dh37fgj492je", Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn", Alg: hawk.SHA256}
+	s := hawk.NewServer(&singleCredentialStore{cred: cred})
+	s.LocaltimeOffset = time.Hour
+	a := hawk.NewAuthorizer(s, hawk.Rule{})
+	a.MaxBodySize = 1 << 20
+
+	return httptest.NewServer(a.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info, _ := hawk.FromContext(r.Context())
+		respond(w, r, info.Credential)
+	})))
+}
+
+func TestRequest(t *testing.T) {
+	ts := newHawkServer(func(w http.ResponseWriter, r *http.Request, cred *hawk.Credential) {
+		s := hawk.NewServer(nil)
+		h, _ := s.Header(r, cred, &hawk.Option{ContentType: "text/plain", Payload: "hello", Ext: "response-specific"})
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Server-Authorization", h)
+		fmt.Fprint(w, "hello")
+	})
+	defer ts.Close()
+
+	file := filepath.Join(t.TempDir(), "body.json")
+	if err := ioutil.WriteFile(file, []byte(`{"a":1}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, stderr := runCommand(t, commandArgs("request",
+		[]string{"-X", "put", "-data", "@" + file, "-content-type", "application/json", "-i"}, ts.URL+"/resource?a=1")...)
+	if code != 0 {
+		t.Fatalf("unexpected exit code %d: %s %s", code, stdout, stderr)
+	}
+	if !strings.HasPrefix(stdout, "HTTP/1.1 200 OK\r\n") || !strings.HasSuffix(stdout, "\r\n\r\nhello") {
+		t.Errorf("unexpected output %q", stdout)
+	}
+	for _, s := range []string{"stale timestamp, clock skew -3600s", "hawk: response verified\n"} {
+		if !strings.Contains(stderr, s) {
+			t.Errorf("expected %q in %q", s, stderr)
+		}
+	}
+
+	code, stdout, _ = runCommand(t, commandArgs("request", []string{"-format", "json", "-retries", "0"}, ts.URL)...)
+	if code != 1 {
+		t.Fatalf("unexpected exit code %d", code)
+	}
+	var out requestOutput
+	if err := json.Unmarshal([]byte(stdout), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Status != http.StatusUnauthorized || out.Verified || out.Attempts != 1 || out.Error != "Missing Server-Authorization header." {
+		t.Errorf("unexpected output %+v", out)
+	}
+}
+
+func TestRequest_BadResponse(t *testing.T) {
+	ts := newHawkServer(func(w http.ResponseWriter, r *http.Request, cred *hawk.Credential) {
+		s := hawk.NewServer(nil)
+		h, _ := s.Header(r, cred, &hawk.Option{ContentType: "text/plain", Payload: "hello"})
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Server-Authorization", h)
+		fmt.Fprint(w, "tampered")
+	})
+	defer ts.Close()
+
+	code, stdout, _ := runCommand(t, commandArgs("request", []string{"-format", "json"}, ts.URL)...)
+	if code != 1 {
+		t.Fatalf("unexpected exit code %d", code)
+	}
+	var out requestOutput
+	if err := json.Unmarshal([]byte(stdout), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Status != http.StatusOK || out.Verified || out.Body != "tampered" || out.Attempts != 2 || out.ClockSkew == nil {
+		t.Errorf("unexpected output %+v", out)
+	}
+}
+
+func TestRequest_PayloadHash(t *testing.T) {
+	tests := []struct {
+		name            string
+		opt             hawk.Option
+		body            string
+		verified        bool
+		payloadVerified bool
+	}{
+		{"hashed", hawk.Option{ContentType: "text/plain", Payload: "hello"}, "hello", true, true},
+		{"not hashed", hawk.Option{}, "hello", true, false},
+		{"truncated", hawk.Option{Hash: (&hawk.PayloadHash{Payload: "hello", Alg: hawk.SHA256}).String()}, "", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newHawkServer(func(w http.ResponseWriter, r *http.Request, cred *hawk.Credential) {
+				h, _ := hawk.NewServer(nil).Header(r, cred, &tt.opt)
+				if tt.opt.ContentType != "" {
+					w.Header().Set("Content-Type", tt.opt.ContentType)
+				}
+				w.Header().Set("Server-Authorization", h)
+				fmt.Fprint(w, tt.body)
+			})
+			defer ts.Close()
+
+			_, stdout, _ := runCommand(t, commandArgs("request", []string{"-format", "json"}, ts.URL)...)
+			var out requestOutput
+			if err := json.Unmarshal([]byte(stdout), &out); err != nil {
+				t.Fatal(err)
+			}
+			if out.Verified != tt.verified || out.PayloadVerified != tt.payloadVerified {
+				t.Errorf("unexpected output %+v", out)
+			}
+		})
+	}
+}
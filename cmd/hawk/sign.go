@@ -0,0 +1,123 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/hiyosi/hawk"
+)
+
+type signOutput struct {
+	Authorization string `json:"authorization"`
+	ID            string `json:"id"`
+	TimeStamp     int64  `json:"ts"`
+	Nonce         string `json:"nonce"`
+	Hash          string `json:"hash,omitempty"`
+	Ext           string `json:"ext,omitempty"`
+	Normalized    string `json:"normalized"`
+}
+
+// signFlags are the flags of the artifacts of a request.
+type signFlags struct {
+	ts                   int64
+	nonce, ext, app, dlg string
+}
+
+func (f *signFlags) register(fs *flag.FlagSet) {
+	fs.Int64Var(&f.ts, "ts", 0, "timestamp in unix seconds (default now)")
+	fs.StringVar(&f.nonce, "nonce", "", "nonce (default random)")
+	fs.StringVar(&f.ext, "ext", "", "application specific data")
+	fs.StringVar(&f.app, "app", "", "application id")
+	fs.StringVar(&f.dlg, "dlg", "", "delegated-by application id")
+}
+
+// option returns the artifacts of the request, with the payload hash if the body has a content type.
+func (f *signFlags) option(cred *hawk.Credential, contentType, body string) (*hawk.Option, error) {
+	opt := &hawk.Option{
+		TimeStamp: f.ts,
+		Nonce:     f.nonce,
+		Ext:       f.ext,
+		App:       f.app,
+		Dlg:       f.dlg,
+	}
+	if opt.TimeStamp == 0 {
+		opt.TimeStamp = time.Now().Unix()
+	}
+	if opt.Nonce == "" {
+		n, err := hawk.Nonce(6)
+		if err != nil {
+			return nil, err
+		}
+		opt.Nonce = n
+	}
+
+	if contentType != "" {
+		opt.ContentType = contentType
+		opt.Payload = body
+		opt.Hash = (&hawk.PayloadHash{ContentType: opt.ContentType, Payload: body, Alg: cred.Alg}).String()
+	}
+	return opt, nil
+}
+
+func runSign(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("sign", stderr, "METHOD URL")
+	var (
+		cf  credentialFlags
+		pf  payloadFlags
+		sf  signFlags
+		out outputFlags
+	)
+	cf.register(fs)
+	pf.register(fs)
+	sf.register(fs)
+	out.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+	if err := out.validate(); err != nil {
+		return fail(stderr, err)
+	}
+	method, rawurl := strings.ToUpper(fs.Arg(0)), fs.Arg(1)
+
+	cred, err := cf.credential()
+	if err != nil {
+		return fail(stderr, err)
+	}
+	body, _, err := pf.payload()
+	if err != nil {
+		return fail(stderr, err)
+	}
+	opt, err := sf.option(cred, pf.contentType, body)
+	if err != nil {
+		return fail(stderr, err)
+	}
+
+	h, err := hawk.NewClient(cred, opt).Header(method, rawurl)
+	if err != nil {
+		return fail(stderr, err)
+	}
+	m := &hawk.Mac{Type: hawk.Header, Credential: cred, Uri: rawurl, Method: method, Option: opt}
+	normalized, err := m.Normalized()
+	if err != nil {
+		return fail(stderr, err)
+	}
+
+	if err := out.write(stdout, h, &signOutput{
+		Authorization: h,
+		ID:            cred.ID,
+		TimeStamp:     opt.TimeStamp,
+		Nonce:         opt.Nonce,
+		Hash:          opt.Hash,
+		Ext:           opt.Ext,
+		Normalized:    normalized,
+	}); err != nil {
+		return fail(stderr, err)
+	}
+	return 0
+}
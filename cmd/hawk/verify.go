@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hiyosi/hawk"
+)
+
+type verifyOutput struct {
+	OK           bool   `json:"ok"`
+	Type         string `json:"type"`
+	ID           string `json:"id,omitempty"`
+	Reason       string `json:"reason,omitempty"`
+	Error        string `json:"error,omitempty"`
+	ClockSkew    *int64 `json:"clock_skew,omitempty"`
+	SuppliedMAC  string `json:"supplied_mac,omitempty"`
+	ExpectedMAC  string `json:"expected_mac,omitempty"`
+	Normalized   string `json:"normalized,omitempty"`
+	SuppliedHash string `json:"supplied_hash,omitempty"`
+	ExpectedHash string `json:"expected_hash,omitempty"`
+}
+
+// singleCredentialStore is a hawk.CredentialStore of one credential.
+type singleCredentialStore struct {
+	cred *hawk.Credential
+}
+
+func (s *singleCredentialStore) GetCredential(id string) (*hawk.Credential, error) {
+	if id != s.cred.ID {
+		return nil, hawk.ErrCredentialNotFound
+	}
+	return s.cred, nil
+}
+
+// recorder is a hawk.Metrics which records the failure reason and the clock skew.
+type recorder struct {
+	reason hawk.FailureReason
+	skew   *time.Duration
+}
+
+func (r *recorder) AuthSucceeded(authType hawk.AuthType, alg hawk.Alg) {}
+func (r *recorder) AuthFailed(authType hawk.AuthType, alg hawk.Alg, reason hawk.FailureReason) {
+	r.reason = reason
+}
+func (r *recorder) NonceReplayed()               {}
+func (r *recorder) ClockSkew(skew time.Duration) { r.skew = &skew }
+
+func runVerify(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("verify", stderr, "METHOD URL")
+	var (
+		cf  credentialFlags
+		pf  payloadFlags
+		out outputFlags
+	)
+	cf.register(fs)
+	pf.register(fs)
+	out.register(fs)
+	header := fs.String("header", "", "Authorization header value. If empty, the bewit parameter of the URL is verified")
+	host := fs.String("host", "", "Host header, if it differs from the host of the URL")
+	now := fs.Int64("now", 0, "server time in unix seconds (default now)")
+	skew := fs.Duration("skew", 60*time.Second, "allowed clock skew")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+	if err := out.validate(); err != nil {
+		return fail(stderr, err)
+	}
+	method, rawurl := strings.ToUpper(fs.Arg(0)), fs.Arg(1)
+
+	cred, err := cf.credential()
+	if err != nil {
+		return fail(stderr, err)
+	}
+	body, hashed, err := pf.payload()
+	if err != nil {
+		return fail(stderr, err)
+	}
+
+	req, err := http.NewRequest(method, rawurl, strings.NewReader(body))
+	if err != nil {
+		return fail(stderr, err)
+	}
+	if *host != "" {
+		req.Host = *host
+	}
+	if *header != "" {
+		req.Header.Set("Authorization", *header)
+	}
+	if hashed {
+		req.Header.Set("Content-Type", pf.contentType)
+	}
+
+	rec := &recorder{}
+	s := hawk.NewServer(&singleCredentialStore{cred: cred})
+	s.Metrics = rec
+	s.TimeStampSkew = *skew
+	if *now != 0 {
+		s.AuthOption = &hawk.AuthOption{CustomClock: nowClock(*now)}
+	}
+	if hashed {
+		s.Payload = body
+	}
+
+	res := &verifyOutput{Type: "header"}
+	if *header == "" {
+		res.Type = "bewit"
+		_, _, err = s.AuthenticateBewitArtifacts(req)
+	} else {
+		_, _, err = s.AuthenticateArtifacts(req)
+	}
+	res.OK = err == nil
+	if err != nil {
+		res.Error = err.Error()
+		res.Reason = string(rec.reason)
+	}
+	if rec.skew != nil {
+		sec := int64(*rec.skew / time.Second)
+		res.ClockSkew = &sec
+	}
+
+	if err := explain(res, cred, req, *header, body, hashed); err != nil {
+		return fail(stderr, err)
+	}
+	if err := out.write(stdout, res.text(), res); err != nil {
+		return fail(stderr, err)
+	}
+	if !res.OK {
+		return 1
+	}
+	return 0
+}
+
+// explain adds the supplied and expected MAC and payload hash, so that a mismatch can be tracked down.
+func explain(res *verifyOutput, cred *hawk.Credential, req *http.Request, header, body string, hashed bool) error {
+	u, err := (&hawk.DefaultTargetResolver{}).ResolveTarget(req)
+	if err != nil {
+		return err
+	}
+	m := &hawk.Mac{Credential: cred, Method: req.Method}
+
+	if res.Type == "bewit" {
+		stripped, bewit := hawk.StripBewit(u)
+		b, err := hawk.ParseBewit(bewit)
+		if err != nil {
+			return nil
+		}
+		u = stripped
+		m.Type = hawk.Bewit
+		m.Uri = u.String()
+		m.Option = &hawk.Option{TimeStamp: b.Exp, Ext: b.Ext}
+		res.ID = b.ID
+		res.SuppliedMAC = b.MAC
+	} else {
+		attrs, err := hawk.ParseHeader(header)
+		if err != nil {
+			return nil
+		}
+		ts, _ := strconv.ParseInt(attrs["ts"], 10, 64)
+		m.Type = hawk.Header
+		m.Uri = u.String()
+		m.Option = &hawk.Option{
+			TimeStamp: ts,
+			Nonce:     attrs["nonce"],
+			Hash:      attrs["hash"],
+			Ext:       attrs["ext"],
+			App:       attrs["app"],
+			Dlg:       attrs["dlg"],
+		}
+		res.ID = attrs["id"]
+		res.SuppliedMAC = attrs["mac"]
+		res.SuppliedHash = attrs["hash"]
+		if hashed {
+			ph := &hawk.PayloadHash{ContentType: req.Header.Get("Content-Type"), Payload: body, Alg: cred.Alg}
+			res.ExpectedHash = ph.String()
+		}
+	}
+
+	if res.Normalized, err = m.Normalized(); err != nil {
+		return err
+	}
+	res.ExpectedMAC, err = m.String()
+	return err
+}
+
+func (r *verifyOutput) text() string {
+	var b strings.Builder
+	if r.OK {
+		fmt.Fprintf(&b, "OK: %s authenticated, id=%s", r.Type, r.ID)
+	} else {
+		fmt.Fprintf(&b, "FAIL: %s: %s", r.Reason, r.Error)
+	}
+	if r.ClockSkew != nil {
+		fmt.Fprintf(&b, "\nclock skew: %ds (client - server)", *r.ClockSkew)
+	}
+	if r.OK {
+		return b.String()
+	}
+	if r.SuppliedMAC != "" || r.ExpectedMAC != "" {
+		fmt.Fprintf(&b, "\nsupplied mac: %s\nexpected mac: %s", r.SuppliedMAC, r.ExpectedMAC)
+	}
+	if r.SuppliedHash != "" || r.ExpectedHash != "" {
+		fmt.Fprintf(&b, "\nsupplied hash: %s\nexpected hash: %s", r.SuppliedHash, r.ExpectedHash)
+	}
+	if r.Normalized != "" {
+		b.WriteString("\nnormalized string:")
+		for _, line := range strings.Split(strings.TrimSuffix(r.Normalized, "\n"), "\n") {
+			b.WriteString("\n  ")
+			b.WriteString(line)
+		}
+	}
+	return b.String()
+}
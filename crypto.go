@@ -1,6 +1,7 @@
 package hawk
 
 import (
+	"bytes"
 	"crypto/hmac"
 	"crypto/sha256"
 	"crypto/sha512"
@@ -10,6 +11,7 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 const headerVersion = 1
@@ -29,6 +31,9 @@ type Mac struct {
 	Method     string
 	HostPort   string
 	Option     *Option
+
+	// url is the parsed Uri. If set, Uri is not parsed again.
+	url *url.URL
 }
 
 type TsMac struct {
@@ -49,36 +54,64 @@ func (m *Mac) String() (string, error) {
 }
 
 func (m *Mac) digest() ([]byte, error) {
-	s := getHash(m.Credential.Alg)
-
-	mac := hmac.New(s, []byte(m.Credential.Key))
-	ns, err := m.normalized()
-	if err != nil {
-		return nil, err
+	u := m.url
+	if u == nil {
+		var err error
+		u, err = url.Parse(m.Uri)
+		if err != nil {
+			return nil, err
+		}
 	}
-	mac.Write([]byte(ns))
 
-	return mac.Sum(nil), nil
+	buf := getBuffer()
+	defer putBuffer(buf)
+	writeNormalized(buf, m.Type, u, m.Method, m.HostPort, m.Option)
+
+	return sign(m.Credential, buf.Bytes())
 }
 
-func (m *Mac) normalized() (string, error) {
+// Normalized returns the normalized string covered by the MAC.
+// It is useful to debug a MAC mismatch with another implementation.
+func (m *Mac) Normalized() (string, error) {
+	if m.url != nil {
+		buf := getBuffer()
+		defer putBuffer(buf)
+		writeNormalized(buf, m.Type, m.url, m.Method, m.HostPort, m.Option)
+		return buf.String(), nil
+	}
 	return normalized(m.Type, m.Uri, m.Method, m.HostPort, m.Option)
 }
 
-// String returns a base64 encoded message authentication code for timestamp
+// String returns a base64 encoded message authentication code for timestamp.
+// It returns an empty string if the Signer of the credential fails.
 func (tm *TsMac) String() string {
-	digest := tm.digest()
+	digest, err := tm.digest()
+	if err != nil {
+		return ""
+	}
 	return base64.StdEncoding.EncodeToString(digest)
 }
 
-func (tm *TsMac) digest() []byte {
-	s := getHash(tm.Credential.Alg)
+func (tm *TsMac) digest() ([]byte, error) {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	tm.writeNormalized(buf)
 
-	mac := hmac.New(s, []byte(tm.Credential.Key))
-	ns := "hawk." + strconv.Itoa(headerVersion) + ".ts" + "\n" + strconv.FormatInt(tm.TimeStamp, 10) + "\n"
-	mac.Write([]byte(ns))
+	return sign(tm.Credential, buf.Bytes())
+}
 
-	return mac.Sum(nil)
+// Normalized returns the normalized string covered by the timestamp MAC.
+func (tm *TsMac) Normalized() string {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	tm.writeNormalized(buf)
+	return buf.String()
+}
+
+func (tm *TsMac) writeNormalized(buf *bytes.Buffer) {
+	buf.WriteString("hawk." + strconv.Itoa(headerVersion) + ".ts\n")
+	writeInt(buf, tm.TimeStamp)
+	buf.WriteByte('\n')
 }
 
 // String returns a base64 encoded hash value of payload
@@ -88,16 +121,69 @@ func (h *PayloadHash) String() string {
 }
 
 func sanitizeContentType(contentType string) string {
-	return strings.TrimSpace(strings.ToLower(strings.Split(contentType, ";")[0]))
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(contentType))
+}
+
+// Normalized returns the normalized string covered by the payload hash.
+func (h *PayloadHash) Normalized() string {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	h.writeNormalized(buf)
+	return buf.String()
+}
+
+func (h *PayloadHash) writeNormalized(buf *bytes.Buffer) {
+	buf.WriteString("hawk." + strconv.Itoa(headerVersion) + ".payload\n")
+	buf.WriteString(sanitizeContentType(h.ContentType))
+	buf.WriteByte('\n')
+	buf.WriteString(h.Payload)
+	buf.WriteByte('\n')
 }
 
 func (h *PayloadHash) hash() []byte {
-	s := getHash(h.Alg)()
+	buf := getBuffer()
+	defer putBuffer(buf)
+	h.writeNormalized(buf)
+
+	p := hashPool(h.Alg)
+	hh := p.Get().(hash.Hash)
+	defer p.Put(hh)
+
+	hh.Reset()
+	hh.Write(buf.Bytes())
+	return hh.Sum(nil)
+}
+
+// payloadHasher computes a payload hash incrementally, for a body which is streamed.
+type payloadHasher struct {
+	h hash.Hash
+}
+
+func newPayloadHasher(alg Alg, contentType string) *payloadHasher {
+	h := sha256.New()
+	if alg == SHA512 {
+		h = sha512.New()
+	}
+	h.Write([]byte("hawk." + strconv.Itoa(headerVersion) + ".payload\n" + sanitizeContentType(contentType) + "\n"))
+	return &payloadHasher{h: h}
+}
 
-	ns := "hawk." + strconv.Itoa(headerVersion) + ".payload" + "\n" + sanitizeContentType(h.ContentType) + "\n" + h.Payload + "\n"
-	s.Write([]byte(ns))
+func (p *payloadHasher) Write(b []byte) (int, error) {
+	return p.h.Write(b)
+}
 
-	return s.Sum(nil)
+// Sum returns the hash of the payload written so far, which must be complete.
+func (p *payloadHasher) Sum() []byte {
+	p.h.Write([]byte{'\n'})
+	return p.h.Sum(nil)
+}
+
+// String returns the base64 encoded hash of the payload written so far, which must be complete.
+func (p *payloadHasher) String() string {
+	return base64.StdEncoding.EncodeToString(p.Sum())
 }
 
 func normalized(authType AuthType, uri, method, customHost string, option *Option) (string, error) {
@@ -106,14 +192,26 @@ func normalized(authType AuthType, uri, method, customHost string, option *Optio
 		return "", err
 	}
 
-	var h string
-	if customHost != "" {
-		h = customHost
-	} else {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	writeNormalized(buf, authType, u, method, customHost, option)
+
+	return buf.String(), nil
+}
+
+// writeNormalized writes the normalized string of the request to buf.
+func writeNormalized(buf *bytes.Buffer, authType AuthType, u *url.URL, method, customHost string, option *Option) {
+	writeNormalizedType(buf, normalizedHeader(authType), u, method, customHost, option)
+}
+
+// writeNormalizedType writes the normalized string of the request to buf, with the type line, e.g. "hawk.1.header".
+func writeNormalizedType(buf *bytes.Buffer, typ string, u *url.URL, method, customHost string, option *Option) {
+	h := customHost
+	if h == "" {
 		h = u.Host
 	}
 
-	host, port, _ := net.SplitHostPort(h)
+	host, port := splitHostPort(h)
 	if port == "" {
 		switch u.Scheme {
 		case "http":
@@ -122,46 +220,122 @@ func normalized(authType AuthType, uri, method, customHost string, option *Optio
 			port = "443"
 		}
 	}
-	if host == "" {
-		if customHost != "" {
-			host = customHost
-		} else {
-			host = u.Host
-		}
-	}
 
-	path := u.Path
-	if u.RawPath != "" {
-		path = u.RawPath
+	buf.WriteString(typ)
+	buf.WriteByte('\n')
+	writeInt(buf, option.TimeStamp)
+	buf.WriteByte('\n')
+	buf.WriteString(option.Nonce)
+	buf.WriteByte('\n')
+	buf.WriteString(strings.ToUpper(method))
+	buf.WriteByte('\n')
+	// the path is written decoded, unless the client sent it with a non-default encoding, e.g. "%2F".
+	p := u.Path
+	if u.RawPath != "" && u.EscapedPath() == u.RawPath {
+		p = u.RawPath
+	}
+	if p != "" {
+		buf.WriteString(p)
+	} else {
+		// the request line of an empty path is "/".
+		buf.WriteByte('/')
 	}
-	if u.Query().Encode() != "" {
-		path = path + "?" + u.RawQuery
+	if hasQuery(u.RawQuery) {
+		buf.WriteByte('?')
+		buf.WriteString(u.RawQuery)
 	}
+	buf.WriteByte('\n')
+	buf.WriteString(strings.ToLower(host))
+	buf.WriteByte('\n')
+	buf.WriteString(port)
+	buf.WriteByte('\n')
+	buf.WriteString(option.Hash)
+	buf.WriteByte('\n')
+	writeEscapedExt(buf, option.Ext)
+	buf.WriteByte('\n')
 
-	header := "hawk" + "." + strconv.Itoa(headerVersion) + "." + strings.ToLower(authType.String())
+	if option.App != "" {
+		buf.WriteString(option.App)
+		buf.WriteByte('\n')
+		buf.WriteString(option.Dlg)
+		buf.WriteByte('\n')
+	}
+}
 
-	ext := ""
-	if option.Ext != "" {
-		ext = strings.Replace(option.Ext, "\\", "\\\\", -1)
-		ext = strings.Replace(ext, "\n", "\\n", -1)
+func normalizedHeader(authType AuthType) string {
+	switch authType {
+	case Header:
+		return "hawk.1.header"
+	case Response:
+		return "hawk.1.response"
+	case Bewit:
+		return "hawk.1.bewit"
+	default:
+		return "hawk." + strconv.Itoa(headerVersion) + "." + strings.ToLower(authType.String())
 	}
+}
 
-	ns := header + "\n" +
-		strconv.FormatInt(option.TimeStamp, 10) + "\n" +
-		option.Nonce + "\n" +
-		strings.ToUpper(method) + "\n" +
-		path + "\n" +
-		strings.ToLower(host) + "\n" +
-		port + "\n" +
-		option.Hash + "\n" +
-		ext + "\n"
+// splitHostPort splits h into host and port.
+// If h does not contain a valid port, the whole value is returned as the host.
+func splitHostPort(h string) (string, string) {
+	if strings.IndexByte(h, ':') < 0 {
+		return h, ""
+	}
+	host, port, err := net.SplitHostPort(h)
+	if err != nil || host == "" {
+		return h, port
+	}
+	return host, port
+}
 
-	if option.App != "" {
-		ns = ns + option.App + "\n"
-		ns = ns + option.Dlg + "\n"
+// hasQuery reports whether url.ParseQuery would find any parameter in the raw query,
+// without building the url.Values.
+func hasQuery(rawQuery string) bool {
+	for rawQuery != "" {
+		var seg string
+		if i := strings.IndexByte(rawQuery, '&'); i >= 0 {
+			seg, rawQuery = rawQuery[:i], rawQuery[i+1:]
+		} else {
+			seg, rawQuery = rawQuery, ""
+		}
+		if seg == "" || strings.IndexByte(seg, ';') >= 0 {
+			continue
+		}
+		if strings.IndexByte(seg, '%') < 0 {
+			return true
+		}
+		key, value := seg, ""
+		if i := strings.IndexByte(seg, '='); i >= 0 {
+			key, value = seg[:i], seg[i+1:]
+		}
+		if _, err := url.QueryUnescape(key); err != nil {
+			continue
+		}
+		if _, err := url.QueryUnescape(value); err != nil {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// writeEscapedExt writes ext with backslashes and newlines escaped.
+func writeEscapedExt(buf *bytes.Buffer, ext string) {
+	for i := 0; i < len(ext); i++ {
+		switch c := ext[i]; c {
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\n':
+			buf.WriteString(`\n`)
+		default:
+			buf.WriteByte(c)
+		}
 	}
+}
 
-	return ns, nil
+func writeInt(buf *bytes.Buffer, i int64) {
+	var b [20]byte
+	buf.Write(strconv.AppendInt(b[:0], i, 10))
 }
 
 func getHash(alg Alg) func() hash.Hash {
@@ -174,3 +348,45 @@ func getHash(alg Alg) func() hash.Hash {
 		return sha256.New
 	}
 }
+
+// maxPooledBufferSize is the maximum capacity of a buffer returned to the pool,
+// so that a large payload does not stay in memory.
+const maxPooledBufferSize = 64 << 10
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+func getBuffer() *bytes.Buffer {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	bufferPool.Put(buf)
+}
+
+var (
+	sha256Pool = sync.Pool{New: func() interface{} { return sha256.New() }}
+	sha512Pool = sync.Pool{New: func() interface{} { return sha512.New() }}
+)
+
+func hashPool(alg Alg) *sync.Pool {
+	if alg == SHA512 {
+		return &sha512Pool
+	}
+	return &sha256Pool
+}
+
+// hmacSum returns the HMAC of data.
+func hmacSum(alg Alg, key string, data []byte) []byte {
+	mac := hmac.New(getHash(alg), []byte(key))
+	mac.Write(data)
+	return mac.Sum(nil)
+}
@@ -1,6 +1,10 @@
 package hawk
 
 import (
+	"bytes"
+	"crypto/hmac"
+	"net/url"
+	"strings"
 	"testing"
 )
 
@@ -20,6 +24,27 @@ func TestMac_String(t *testing.T) {
 			uri:    "http://example.com:8000/resource/x%2Fy%2Fz?b=1&a=2",
 			expect: "nurs0/PPVGhFt9v2gzBP4BCRQwzQJwPuIQKLYjoVIQ0=",
 		},
+		{
+			name:   "default encoded uri",
+			uri:    "http://example.com:8000/resource/x%20y?b=1&a=2",
+			expect: "tEv9HLgoRL4tZWnncml4qvicqnVn/3gK/J2ovEbfHM8=",
+		},
+		{
+			name:   "non-ascii uri",
+			uri:    "http://example.com:8000/resource/%E3%81%82?b=1&a=2",
+			expect: "rR4gz9upfPDDDe2mvQOcio40Ng5jNhAaBugikffuLs0=",
+		},
+		{
+			// signed as "/", the path of the request line.
+			name:   "empty path",
+			uri:    "http://example.com:8000?b=1&a=2",
+			expect: "K2oDHsKb18Dv7wJwbCYtijAszbyPWaS3pNd1ZasQnbM=",
+		},
+		{
+			name:   "root path",
+			uri:    "http://example.com:8000/?b=1&a=2",
+			expect: "K2oDHsKb18Dv7wJwbCYtijAszbyPWaS3pNd1ZasQnbM=",
+		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			m := &Mac{
@@ -182,3 +207,31 @@ func TestPayloadHash_String(t *testing.T) {
 		t.Error("invalid payload hash string when given ContentType with parameters.")
 	}
 }
+
+func Test_hmacSum(t *testing.T) {
+	data := []byte("hawk.1.header\n1353832234\nj4h3g2\nGET\n/resource/1?b=1&a=2\nexample.com\n8000\n\nsome-app-ext-data\n")
+
+	for _, alg := range []Alg{SHA256, SHA512} {
+		for _, key := range []string{"", "short-key", strings.Repeat("k", 64), strings.Repeat("k", 200)} {
+			mac := hmac.New(getHash(alg), []byte(key))
+			mac.Write(data)
+			expect := mac.Sum(nil)
+
+			if act := hmacSum(alg, key, data); !bytes.Equal(act, expect) {
+				t.Errorf("invalid hmac: alg=%s, len(key)=%d", alg, len(key))
+			}
+		}
+	}
+}
+
+func Test_hasQuery(t *testing.T) {
+	for _, q := range []string{"", "a=1", "&", "&&a", "=x", "a;b", "a=%zz", "%zz=1&b", "a=%41", ";&;"} {
+		expect := false
+		if v, _ := url.ParseQuery(q); v.Encode() != "" {
+			expect = true
+		}
+		if act := hasQuery(q); act != expect {
+			t.Errorf("unexpected result for %q: actual=%v, expect=%v", q, act, expect)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package hawk
+
+import (
+	"crypto/hmac"
+	"encoding/base64"
+	"errors"
+	"hash"
+	"strings"
+)
+
+// MasterSecret is a versioned master secret of DerivedCredentialStore.
+type MasterSecret struct {
+	Version string
+	Secret  []byte
+}
+
+// DerivedCredentialStore is a CredentialStore which derives the key of every credential
+// from a master secret with HKDF (RFC 5869), so that no key has to be stored.
+//
+// The id of a credential is "<version>:<name>", where the version selects the master secret.
+// The key is the base64url encoded HKDF output, with the master secret as the input keying material
+// and Info and the id as the info, e.g. HKDF-SHA256(master, "", Info + "\x00" + "v1:device-42").
+// The hash of HKDF is the one of Alg, and the length of the key is its output size.
+//
+// To rotate the master secret, add a new version: Provision uses the last one, and credentials
+// derived from the previous ones keep working until their master secret is removed.
+type DerivedCredentialStore struct {
+	Masters []MasterSecret
+	// Info binds the keys to a context, e.g. "hawk device keys". Changing it changes every key.
+	Info string
+	Alg  Alg
+}
+
+// NewDerivedCredentialStore initializes a new DerivedCredentialStore.
+// The last master secret is the current one.
+func NewDerivedCredentialStore(info string, alg Alg, masters ...MasterSecret) (*DerivedCredentialStore, error) {
+	if len(masters) == 0 {
+		return nil, errors.New("No master secret.")
+	}
+	seen := make(map[string]bool)
+	for _, m := range masters {
+		if m.Version == "" || strings.Contains(m.Version, ":") {
+			return nil, errors.New("Invalid master secret version: " + m.Version)
+		}
+		if seen[m.Version] {
+			return nil, errors.New("Duplicate master secret version: " + m.Version)
+		}
+		if len(m.Secret) == 0 {
+			return nil, errors.New("Empty master secret: " + m.Version)
+		}
+		seen[m.Version] = true
+	}
+	return &DerivedCredentialStore{
+		Masters: masters,
+		Info:    info,
+		Alg:     alg,
+	}, nil
+}
+
+func (s *DerivedCredentialStore) GetCredential(id string) (*Credential, error) {
+	i := strings.IndexByte(id, ':')
+	if i < 0 || i == len(id)-1 {
+		return nil, ErrCredentialNotFound
+	}
+	m := s.master(id[:i])
+	if m == nil {
+		return nil, ErrCredentialNotFound
+	}
+	return s.derive(m, id), nil
+}
+
+// Provision returns the credential for the name, derived from the current master secret.
+func (s *DerivedCredentialStore) Provision(name string) (*Credential, error) {
+	if name == "" {
+		return nil, errors.New("Empty name.")
+	}
+	if len(s.Masters) == 0 {
+		return nil, errors.New("No master secret.")
+	}
+	m := &s.Masters[len(s.Masters)-1]
+	return s.derive(m, m.Version+":"+name), nil
+}
+
+func (s *DerivedCredentialStore) master(version string) *MasterSecret {
+	for i := range s.Masters {
+		if s.Masters[i].Version == version {
+			return &s.Masters[i]
+		}
+	}
+	return nil
+}
+
+func (s *DerivedCredentialStore) derive(m *MasterSecret, id string) *Credential {
+	h := getHash(s.Alg)
+	info := make([]byte, 0, len(s.Info)+1+len(id))
+	info = append(info, s.Info...)
+	info = append(info, 0)
+	info = append(info, id...)
+
+	key := hkdf(h, m.Secret, nil, info, h().Size())
+	return &Credential{
+		ID:  id,
+		Key: base64.RawURLEncoding.EncodeToString(key),
+		Alg: s.Alg,
+	}
+}
+
+// hkdf returns length bytes of the HKDF (RFC 5869) output. length must not exceed 255 times the hash size.
+func hkdf(h func() hash.Hash, secret, salt, info []byte, length int) []byte {
+	if salt == nil {
+		salt = make([]byte, h().Size())
+	}
+	extract := hmac.New(h, salt)
+	extract.Write(secret)
+	prk := extract.Sum(nil)
+
+	expand := hmac.New(h, prk)
+	out := make([]byte, 0, length+expand.Size())
+	var t []byte
+	for counter := byte(1); len(out) < length; counter++ {
+		expand.Reset()
+		expand.Write(t)
+		expand.Write(info)
+		expand.Write([]byte{counter})
+		t = expand.Sum(t[:0])
+		out = append(out, t...)
+	}
+	return out[:length]
+}
@@ -0,0 +1,114 @@
+package hawk
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func Test_hkdf(t *testing.T) {
+	// RFC 5869, A.1 and A.3
+	for _, tc := range []struct {
+		ikm, salt, info string
+		length          int
+		okm             string
+	}{
+		{
+			ikm:    "0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b",
+			salt:   "000102030405060708090a0b0c",
+			info:   "f0f1f2f3f4f5f6f7f8f9",
+			length: 42,
+			okm:    "3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf34007208d5b887185865",
+		},
+		{
+			ikm:    "0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b",
+			length: 42,
+			okm:    "8da4e775a563c18f715f802a063c5a31b8a11f5c5ee1879ec3454e5f3c738d2d9d201395faa4b61a96c8",
+		},
+	} {
+		ikm, _ := hex.DecodeString(tc.ikm)
+		salt, _ := hex.DecodeString(tc.salt)
+		info, _ := hex.DecodeString(tc.info)
+		expect, _ := hex.DecodeString(tc.okm)
+		if act := hkdf(sha256.New, ikm, salt, info, tc.length); !bytes.Equal(act, expect) {
+			t.Errorf("unexpected okm: %x", act)
+		}
+	}
+}
+
+func TestDerivedCredentialStore(t *testing.T) {
+	v1 := MasterSecret{Version: "v1", Secret: []byte("master-secret-1-werxhqb98rpaxn39848xrunpaw")}
+	v2 := MasterSecret{Version: "v2", Secret: []byte("master-secret-2-werxhqb98rpaxn39848xrunpaw")}
+
+	old, err := NewDerivedCredentialStore("devices", SHA256, v1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewDerivedCredentialStore("devices", SHA256, v1, v2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// credentials provisioned before the rotation keep working
+	c1, _ := old.Provision("device-42")
+	if c1.ID != "v1:device-42" {
+		t.Errorf("unexpected id: %s", c1.ID)
+	}
+	act, err := s.GetCredential(c1.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if act.Key != c1.Key || act.Alg != SHA256 {
+		t.Errorf("unexpected credential: %+v", act)
+	}
+
+	c2, _ := s.Provision("device-42")
+	if c2.ID != "v2:device-42" || c2.Key == c1.Key {
+		t.Errorf("unexpected credential: %+v", c2)
+	}
+	if len(c2.Key) != 43 {
+		t.Errorf("unexpected key length: %d", len(c2.Key))
+	}
+
+	// keys depend on the id and the info
+	other, _ := s.Provision("device-43")
+	if other.Key == c2.Key {
+		t.Error("different ids have the same key")
+	}
+	s1, _ := NewDerivedCredentialStore("other", SHA256, v1, v2)
+	if c, _ := s1.Provision("device-42"); c.Key == c2.Key {
+		t.Error("different infos have the same key")
+	}
+
+	for _, id := range []string{"device-42", "v3:device-42", "v1:", ""} {
+		if _, err := s.GetCredential(id); err == nil {
+			t.Errorf("expected an error for %q, but got nil", id)
+		}
+	}
+
+	// the server authenticates derived credentials
+	c := NewClient(c2, &Option{TimeStamp: time.Now().Unix(), Nonce: "3hOHpR"})
+	h, _ := c.Header("GET", "http://example.com/resource/1")
+	r, _ := http.NewRequest("GET", "http://example.com/resource/1", nil)
+	r.Header.Set("Authorization", h)
+	if _, err := NewServer(s).Authenticate(r); err != nil {
+		t.Errorf("return error, %s", err)
+	}
+}
+
+func TestNewDerivedCredentialStore_Invalid(t *testing.T) {
+	for _, masters := range [][]MasterSecret{
+		nil,
+		{{Version: "", Secret: []byte("a")}},
+		{{Version: "v:1", Secret: []byte("a")}},
+		{{Version: "v1", Secret: nil}},
+		{{Version: "v1", Secret: []byte("a")}, {Version: "v1", Secret: []byte("b")}},
+	} {
+		if _, err := NewDerivedCredentialStore("devices", SHA256, masters...); err == nil {
+			t.Errorf("expected an error for %v, but got nil", masters)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package hawk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// FileCredentialStore is a CredentialStore which reads the credentials from a JSON file,
+// an array of credentials encoded as by Credential.MarshalJSON, e.g.
+//
+//	[
+//	  {"id": "partner-1", "key": "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn", "alg": "sha256"},
+//	  {"id": "partner-2", "key": "...", "alg": "sha512", "metadata": {"tenant": "example"}}
+//	]
+//
+// The file is reloaded with Reload, Watch or ReloadOnSignal. A reload replaces all the credentials at once,
+// and if the file is invalid, the previous credentials are kept.
+// It is safe for concurrent use.
+type FileCredentialStore struct {
+	Path string
+	// KeyPolicy, if set, rejects a file with a weak key.
+	KeyPolicy *KeyPolicy
+	// OnReload, if set, is called with the result of every reload, e.g. to log the failures.
+	OnReload func(err error)
+
+	mu          sync.Mutex // serializes reloads
+	credentials atomic.Value
+	modTime     time.Time
+	size        int64
+}
+
+// NewFileCredentialStore initializes a new FileCredentialStore, and loads the file.
+// To validate the keys of the first load, set KeyPolicy on a FileCredentialStore and call Reload instead.
+func NewFileCredentialStore(path string) (*FileCredentialStore, error) {
+	s := &FileCredentialStore{Path: path}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileCredentialStore) GetCredential(id string) (*Credential, error) {
+	creds, _ := s.credentials.Load().(map[string]*Credential)
+	c, ok := creds[id]
+	if !ok {
+		return nil, ErrCredentialNotFound
+	}
+	return c.Clone(), nil
+}
+
+// Len returns the number of the credentials.
+func (s *FileCredentialStore) Len() int {
+	creds, _ := s.credentials.Load().(map[string]*Credential)
+	return len(creds)
+}
+
+// Reload reads the file, and replaces the credentials if it is valid.
+func (s *FileCredentialStore) Reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.reload()
+	if s.OnReload != nil {
+		s.OnReload(err)
+	}
+	return err
+}
+
+func (s *FileCredentialStore) reload() error {
+	fi, err := os.Stat(s.Path)
+	if err != nil {
+		return err
+	}
+	// an invalid file is not retried by Watch until it is modified again.
+	s.modTime = fi.ModTime()
+	s.size = fi.Size()
+
+	data, err := ioutil.ReadFile(s.Path)
+	if err != nil {
+		return err
+	}
+	creds, err := parseCredentials(data, s.KeyPolicy)
+	if err != nil {
+		return errors.New("Invalid credential file " + s.Path + ": " + err.Error())
+	}
+
+	s.credentials.Store(creds)
+	return nil
+}
+
+func parseCredentials(data []byte, policy *KeyPolicy) (map[string]*Credential, error) {
+	var list []*Credential
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+
+	creds := make(map[string]*Credential, len(list))
+	for i, c := range list {
+		if c == nil || c.ID == "" {
+			return nil, errors.New("Missing id at index " + strconv.Itoa(i) + ".")
+		}
+		if _, ok := creds[c.ID]; ok {
+			return nil, errors.New("Duplicate id " + c.ID + ".")
+		}
+		if c.Key == "" {
+			return nil, errors.New("Missing key for " + c.ID + ".")
+		}
+		if c.Alg != SHA256 && c.Alg != SHA512 {
+			return nil, errors.New("Missing alg for " + c.ID + ".")
+		}
+		if policy != nil {
+			if err := policy.Validate(c); err != nil {
+				return nil, errors.New(err.Error() + " (" + c.ID + ")")
+			}
+		}
+		creds[c.ID] = c
+	}
+	return creds, nil
+}
+
+// changed reports whether the file has been modified since the last load.
+func (s *FileCredentialStore) changed() bool {
+	fi, err := os.Stat(s.Path)
+	if err != nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !fi.ModTime().Equal(s.modTime) || fi.Size() != s.size
+}
+
+// Watch checks the file every interval, and reloads it when it has been modified, until ctx is done.
+func (s *FileCredentialStore) Watch(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if s.changed() {
+				s.Reload()
+			}
+		}
+	}
+}
+
+// ReloadOnSignal reloads the file on the signals, syscall.SIGHUP if none is given, until ctx is done.
+func (s *FileCredentialStore) ReloadOnSignal(ctx context.Context, sig ...os.Signal) {
+	if len(sig) == 0 {
+		// signal.Notify without signals would relay every signal, and the process would not stop on SIGINT.
+		sig = []os.Signal{syscall.SIGHUP}
+	}
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, sig...)
+	defer signal.Stop(c)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-c:
+			s.Reload()
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package hawk
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testCredentialFile = `[
+  {"id": "partner-1", "key": "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn", "alg": "sha256"},
+  {"id": "partner-2", "key": "jg8U2oFrPbhn6ZG0JdgXUT2BnGyEUsNmSUbWIiA0lQQ", "alg": "sha512", "metadata": {"tenant": "example"}}
+]`
+
+func TestFileCredentialStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	if err := ioutil.WriteFile(path, []byte(testCredentialFile), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var reloads []error
+	s, err := NewFileCredentialStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.OnReload = func(err error) { reloads = append(reloads, err) }
+
+	c, err := s.GetCredential("partner-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Alg != SHA512 || c.Metadata["tenant"] != "example" {
+		t.Errorf("unexpected credential: %v", c)
+	}
+	c.Metadata["tenant"] = "other"
+	if c, _ := s.GetCredential("partner-2"); c.Metadata["tenant"] != "example" {
+		t.Errorf("the store shares the metadata: %v", c.Metadata)
+	}
+	if _, err := s.GetCredential("partner-3"); err == nil {
+		t.Error("expected an error for unknown id, but got nil")
+	}
+
+	// invalid files are rejected, and the previous credentials are kept.
+	for _, content := range []string{
+		`[{"id": "partner-1", "key": "a"`,
+		`[{"id": "", "key": "a", "alg": "sha256"}]`,
+		`[{"id": "a", "key": "", "alg": "sha256"}]`,
+		`[{"id": "a", "key": "b"}]`,
+		`[{"id": "a", "key": "b", "alg": "md5"}]`,
+		`[{"id": "a", "key": "b", "alg": "sha256"}, {"id": "a", "key": "c", "alg": "sha256"}]`,
+	} {
+		ioutil.WriteFile(path, []byte(content), 0600)
+		if err := s.Reload(); err == nil {
+			t.Errorf("expected an error for %s, but got nil", content)
+		}
+		if s.Len() != 2 {
+			t.Errorf("previous credentials are not kept: %d", s.Len())
+		}
+	}
+
+	// weak keys are rejected with a KeyPolicy
+	s.KeyPolicy = DefaultKeyPolicy
+	ioutil.WriteFile(path, []byte(`[{"id": "a", "key": "test-key", "alg": "sha256"}]`), 0600)
+	if err := s.Reload(); err == nil {
+		t.Error("expected an error for a weak key, but got nil")
+	}
+
+	ioutil.WriteFile(path, []byte(`[{"id": "partner-3", "key": "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn", "alg": "sha256"}]`), 0600)
+	if err := s.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetCredential("partner-1"); err == nil {
+		t.Error("removed credential is still returned")
+	}
+	if _, err := s.GetCredential("partner-3"); err != nil {
+		t.Errorf("return error, %s", err)
+	}
+	if len(reloads) != 8 || reloads[7] != nil {
+		t.Errorf("unexpected reloads: %v", reloads)
+	}
+
+	if _, err := NewFileCredentialStore(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("expected an error for missing file, but got nil")
+	}
+}
+
+func TestFileCredentialStore_Watch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	ioutil.WriteFile(path, []byte(testCredentialFile), 0600)
+	s, err := NewFileCredentialStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded := make(chan error, 1)
+	s.OnReload = func(err error) { reloaded <- err }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Watch(ctx, 10*time.Millisecond)
+
+	// concurrent lookups during the reload
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				s.GetCredential("partner-1")
+			}
+		}()
+	}
+
+	// the file is replaced atomically, so that a partially written file is not seen.
+	tmp := path + ".tmp"
+	ioutil.WriteFile(tmp, []byte(`[{"id": "partner-3", "key": "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn", "alg": "sha256"}]`), 0600)
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-reloaded:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("file is not reloaded")
+	}
+	wg.Wait()
+
+	if _, err := s.GetCredential("partner-3"); err != nil {
+		t.Errorf("return error, %s", err)
+	}
+}
@@ -0,0 +1,199 @@
+// Package hawktest provides utilities for testing code which uses Hawk authentication.
+package hawktest
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hiyosi/hawk"
+)
+
+// FixedClock is a hawk.Clock which always returns the same time.
+type FixedClock int64
+
+// Now returns the fixed unix-time obtained by adding the offset value.
+func (c FixedClock) Now(offset time.Duration) int64 {
+	return time.Unix(int64(c), 0).Add(offset).Unix()
+}
+
+// SteppableClock is a hawk.Clock whose time is moved explicitly.
+// It is safe for concurrent use.
+type SteppableClock struct {
+	mu  sync.Mutex
+	now int64
+}
+
+// NewSteppableClock initializes a new SteppableClock with the given unix-time.
+func NewSteppableClock(now int64) *SteppableClock {
+	return &SteppableClock{now: now}
+}
+
+// Now returns the current unix-time of the clock obtained by adding the offset value.
+func (c *SteppableClock) Now(offset time.Duration) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return time.Unix(c.now, 0).Add(offset).Unix()
+}
+
+// Step moves the clock by d.
+func (c *SteppableClock) Step(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = time.Unix(c.now, 0).Add(d).Unix()
+}
+
+// Set sets the clock to the given unix-time.
+func (c *SteppableClock) Set(now int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+// Nonces returns deterministic nonces: prefix+"1", prefix+"2", ...
+// It is safe for concurrent use.
+type Nonces struct {
+	Prefix string
+
+	mu sync.Mutex
+	n  int
+}
+
+// Next returns the next nonce.
+func (n *Nonces) Next() string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.n++
+	return n.Prefix + strconv.Itoa(n.n)
+}
+
+// CredentialStore is a static hawk.CredentialStore.
+type CredentialStore struct {
+	mu          sync.RWMutex
+	credentials map[string]*hawk.Credential
+}
+
+// NewCredentialStore initializes a new CredentialStore holding the given credentials.
+func NewCredentialStore(creds ...*hawk.Credential) *CredentialStore {
+	cs := &CredentialStore{
+		credentials: make(map[string]*hawk.Credential),
+	}
+	for _, c := range creds {
+		cs.Add(c)
+	}
+	return cs
+}
+
+// Add adds or replaces a credential.
+func (cs *CredentialStore) Add(c *hawk.Credential) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.credentials[c.ID] = c.Clone()
+}
+
+// GetCredential returns a copy of the credential for the id.
+func (cs *CredentialStore) GetCredential(id string) (*hawk.Credential, error) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	c, ok := cs.credentials[id]
+	if !ok {
+		return nil, hawk.ErrCredentialNotFound
+	}
+	return c.Clone(), nil
+}
+
+// NonceCall is a call to NonceValidator.Validate.
+type NonceCall struct {
+	Key       string
+	Nonce     string
+	TimeStamp int64
+	Result    bool
+}
+
+// NonceValidator is a hawk.NonceValidator which records the calls.
+// It rejects a nonce which has already been seen for the same key and timestamp.
+type NonceValidator struct {
+	// AllowReplay disables the replay detection.
+	AllowReplay bool
+
+	mu    sync.Mutex
+	calls []NonceCall
+	seen  map[string]bool
+}
+
+func (v *NonceValidator) Validate(key, nonce string, ts int64) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.seen == nil {
+		v.seen = make(map[string]bool)
+	}
+	k := key + "\n" + nonce + "\n" + strconv.FormatInt(ts, 10)
+	result := v.AllowReplay || !v.seen[k]
+	v.seen[k] = true
+
+	v.calls = append(v.calls, NonceCall{Key: key, Nonce: nonce, TimeStamp: ts, Result: result})
+	return result
+}
+
+// Calls returns the recorded calls.
+func (v *NonceValidator) Calls() []NonceCall {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	calls := make([]NonceCall, len(v.calls))
+	copy(calls, v.calls)
+	return calls
+}
+
+// NewRequest returns a *http.Request with a Hawk Authorization header.
+// The TimeStamp, Nonce, Ext, App and Dlg of opt are used for the header.
+// If opt.ContentType is set, the body is sent with the Content-Type and covered by the payload hash.
+func NewRequest(cred *hawk.Credential, opt *hawk.Option, method, rawurl string, body []byte) (*http.Request, error) {
+	o := *opt
+	o.Hash = ""
+	o.Payload = ""
+	if o.ContentType != "" {
+		o.Payload = string(body)
+	}
+
+	c := hawk.NewClient(cred, &o)
+	h, err := c.Header(method, rawurl)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(method, rawurl, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", h)
+	if o.ContentType != "" {
+		req.Header.Set("Content-Type", o.ContentType)
+	}
+	return req, nil
+}
+
+// BewitURL returns the URL with a bewit parameter valid for ttl from the clock's time.
+func BewitURL(cred *hawk.Credential, rawurl string, ttl time.Duration, clock hawk.Clock) (string, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return "", err
+	}
+
+	b := hawk.NewBewitConfig(cred, ttl)
+	bewit := b.GetBewit(rawurl, clock)
+	if bewit == "" {
+		return "", errors.New("Failed to build bewit.")
+	}
+
+	if u.RawQuery == "" {
+		u.RawQuery = "bewit=" + bewit
+	} else {
+		u.RawQuery = u.RawQuery + "&bewit=" + bewit
+	}
+	return u.String(), nil
+}
@@ -0,0 +1,109 @@
+package hawktest
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hiyosi/hawk"
+)
+
+var testCredential = &hawk.Credential{
+	ID:  "dh37fgj492je",
+	Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn",
+	Alg: hawk.SHA256,
+}
+
+func TestClocks(t *testing.T) {
+	fc := FixedClock(1365711458)
+	if act := fc.Now(time.Minute); act != 1365711518 {
+		t.Errorf("unexpected time: %d", act)
+	}
+
+	sc := NewSteppableClock(1365711458)
+	sc.Step(30 * time.Second)
+	if act := sc.Now(0); act != 1365711488 {
+		t.Errorf("unexpected time: %d", act)
+	}
+	sc.Set(1)
+	if act := sc.Now(-time.Second); act != 0 {
+		t.Errorf("unexpected time: %d", act)
+	}
+}
+
+func TestNonces(t *testing.T) {
+	n := &Nonces{Prefix: "nonce-"}
+	if a, b := n.Next(), n.Next(); a != "nonce-1" || b != "nonce-2" {
+		t.Errorf("unexpected nonces: %s, %s", a, b)
+	}
+}
+
+func TestCredentialStore(t *testing.T) {
+	cs := NewCredentialStore(testCredential)
+
+	c, err := cs.GetCredential(testCredential.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Key != testCredential.Key || c == testCredential {
+		t.Error("expected a copy of the credential")
+	}
+
+	if _, err := cs.GetCredential("unknown"); err == nil {
+		t.Error("expected an error for unknown id, but got nil")
+	}
+}
+
+func TestNonceValidator(t *testing.T) {
+	v := &NonceValidator{}
+	if !v.Validate("key", "abc", 1) {
+		t.Error("expected valid")
+	}
+	if v.Validate("key", "abc", 1) {
+		t.Error("expected replay to be rejected")
+	}
+	if !v.Validate("key", "abc", 2) {
+		t.Error("expected valid")
+	}
+
+	calls := v.Calls()
+	if len(calls) != 3 || calls[1].Result || calls[1].Nonce != "abc" {
+		t.Errorf("unexpected calls: %+v", calls)
+	}
+}
+
+func TestNewRequest(t *testing.T) {
+	clock := FixedClock(1365711458)
+	s := hawk.NewServer(NewCredentialStore(testCredential))
+	s.AuthOption = &hawk.AuthOption{CustomClock: clock}
+	s.Payload = `{"a":1}`
+
+	req, err := NewRequest(testCredential, &hawk.Option{
+		TimeStamp:   clock.Now(0),
+		Nonce:       "abc",
+		ContentType: "application/json",
+	}, "POST", "http://example.com/resource", []byte(`{"a":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Authenticate(req); err != nil {
+		t.Errorf("return error, %s", err)
+	}
+}
+
+func TestBewitURL(t *testing.T) {
+	clock := FixedClock(1365711458)
+	s := hawk.NewServer(NewCredentialStore(testCredential))
+	s.AuthOption = &hawk.AuthOption{CustomClock: clock}
+
+	u, err := BewitURL(testCredential, "http://example.com/resource", time.Minute, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest("GET", u, nil)
+	if _, err := s.AuthenticateBewit(req); err != nil {
+		t.Errorf("return error, %s", err)
+	}
+}
@@ -0,0 +1,89 @@
+package hawktest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/hiyosi/hawk"
+)
+
+// Record is a request received by Server.
+type Record struct {
+	Request    *http.Request
+	Credential *hawk.Credential
+	Artifacts  *hawk.Option
+	Bewit      bool
+	Err        error
+}
+
+// Server is a httptest.Server which authenticates every request with a hawk.Server.
+//
+// Requests with a bewit parameter are authenticated with AuthenticateBewit, others with Authenticate.
+// Authenticated requests are passed to the handler, others get a 401 response.
+// Every request is recorded.
+type Server struct {
+	*httptest.Server
+
+	Hawk    *hawk.Server
+	Handler http.Handler
+
+	mu      sync.Mutex
+	records []Record
+}
+
+// NewServer starts and returns a new Server.
+// If handler is nil, authenticated requests get an empty 200 response.
+// The caller should call Close when finished, to shut it down.
+func NewServer(s *hawk.Server, handler http.Handler) *Server {
+	ts := &Server{
+		Hawk:    s,
+		Handler: handler,
+	}
+	ts.Server = httptest.NewServer(http.HandlerFunc(ts.serveHTTP))
+	return ts
+}
+
+func (ts *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	rec := Record{Request: r}
+	if r.URL.Query().Get("bewit") != "" {
+		rec.Bewit = true
+		rec.Credential, rec.Artifacts, rec.Err = ts.Hawk.AuthenticateBewitArtifacts(r)
+	} else {
+		rec.Credential, rec.Artifacts, rec.Err = ts.Hawk.AuthenticateArtifacts(r)
+	}
+
+	ts.mu.Lock()
+	ts.records = append(ts.records, rec)
+	ts.mu.Unlock()
+
+	if rec.Err != nil {
+		w.Header().Set("WWW-Authenticate", "Hawk")
+		http.Error(w, rec.Err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if ts.Handler == nil {
+		return
+	}
+	ts.Handler.ServeHTTP(w, r)
+}
+
+// Records returns the recorded requests.
+func (ts *Server) Records() []Record {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	records := make([]Record, len(ts.records))
+	copy(records, ts.records)
+	return records
+}
+
+// LastRecord returns the last recorded request, or nil if there is none.
+func (ts *Server) LastRecord() *Record {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if len(ts.records) == 0 {
+		return nil
+	}
+	rec := ts.records[len(ts.records)-1]
+	return &rec
+}
@@ -0,0 +1,76 @@
+package hawktest
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hiyosi/hawk"
+)
+
+func TestServer(t *testing.T) {
+	clock := NewSteppableClock(1365711458)
+	nonces := &Nonces{Prefix: "n"}
+
+	s := hawk.NewServer(NewCredentialStore(testCredential))
+	s.AuthOption = &hawk.AuthOption{CustomClock: clock}
+	s.NonceValidator = &NonceValidator{}
+
+	ts := NewServer(s, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	}))
+	defer ts.Close()
+
+	req, _ := NewRequest(testCredential, &hawk.Option{
+		TimeStamp: clock.Now(0),
+		Nonce:     nonces.Next(),
+		Ext:       "some-app-data",
+	}, "GET", ts.URL+"/resource?a=1", nil)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("unexpected status: %d", res.StatusCode)
+	}
+
+	rec := ts.LastRecord()
+	if rec == nil || rec.Err != nil || rec.Bewit {
+		t.Fatalf("unexpected record: %+v", rec)
+	}
+	if rec.Credential.ID != testCredential.ID || rec.Artifacts.Nonce != "n1" || rec.Artifacts.Ext != "some-app-data" {
+		t.Errorf("unexpected record: %+v, %+v", rec.Credential, rec.Artifacts)
+	}
+
+	// stale request
+	clock.Step(time.Hour)
+	req1, _ := NewRequest(testCredential, &hawk.Option{
+		TimeStamp: clock.Now(-2 * time.Minute),
+		Nonce:     nonces.Next(),
+	}, "GET", ts.URL+"/resource", nil)
+	res1, err := http.DefaultClient.Do(req1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res1.Body.Close()
+	if res1.StatusCode != http.StatusUnauthorized || res1.Header.Get("WWW-Authenticate") != "Hawk" {
+		t.Errorf("unexpected response: %d", res1.StatusCode)
+	}
+
+	// bewit
+	u, _ := BewitURL(testCredential, ts.URL+"/download", time.Minute, clock)
+	res2, err := http.Get(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res2.Body.Close()
+	if res2.StatusCode != http.StatusOK {
+		t.Errorf("unexpected status: %d", res2.StatusCode)
+	}
+
+	records := ts.Records()
+	if len(records) != 3 || records[1].Err == nil || !records[2].Bewit || records[2].Err != nil {
+		t.Errorf("unexpected records: %+v", records)
+	}
+}
@@ -0,0 +1,77 @@
+package hawktest
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"hash"
+	"sync"
+
+	"github.com/hiyosi/hawk"
+)
+
+// Signer is an in-process stand-in for a remote hawk.Signer, e.g. backed by a KMS.
+// It holds the keys by credential id, so that the credentials used by the code under test have no key.
+// It is safe for concurrent use.
+type Signer struct {
+	// Err, if set, is returned by Sign, e.g. to test the handling of an unavailable KMS.
+	Err error
+
+	mu    sync.Mutex
+	keys  map[string]string
+	calls int
+}
+
+// NewSigner initializes a new Signer.
+func NewSigner() *Signer {
+	return &Signer{keys: make(map[string]string)}
+}
+
+// Add stores the key of the credential, and returns a copy of the credential
+// with the Signer and without the key.
+func (s *Signer) Add(cred *hawk.Credential) *hawk.Credential {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[cred.ID] = cred.Key
+
+	c := cred.Clone()
+	c.Key = ""
+	c.Signer = s
+	return c
+}
+
+// Sign returns the HMAC of the normalized string with the key stored for the credential id.
+func (s *Signer) Sign(cred *hawk.Credential, normalized []byte) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	key, ok := s.keys[cred.ID]
+	if !ok {
+		return nil, errors.New("Key not found.")
+	}
+
+	var h func() hash.Hash
+	switch cred.Alg {
+	case hawk.SHA256:
+		h = sha256.New
+	case hawk.SHA512:
+		h = sha512.New
+	default:
+		return nil, errors.New("Invalid alg.")
+	}
+	mac := hmac.New(h, []byte(key))
+	mac.Write(normalized)
+	return mac.Sum(nil), nil
+}
+
+// Calls returns the number of calls to Sign.
+func (s *Signer) Calls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
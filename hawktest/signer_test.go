@@ -0,0 +1,77 @@
+package hawktest
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hiyosi/hawk"
+)
+
+func TestSigner(t *testing.T) {
+	clock := FixedClock(1365711458)
+	signer := NewSigner()
+	cred := signer.Add(testCredential)
+	if cred.Key != "" || cred.Signer == nil {
+		t.Fatalf("unexpected credential: %+v", cred)
+	}
+
+	s := hawk.NewServer(NewCredentialStore(cred))
+	s.AuthOption = &hawk.AuthOption{CustomClock: clock}
+	s.NonceValidator = &NonceValidator{}
+
+	// the MACs are the same as with the key
+	opt := &hawk.Option{TimeStamp: clock.Now(0), Nonce: "n1", Ext: "some-app-data"}
+	h, err := hawk.NewClient(cred, opt).Header("GET", "http://example.com/resource?a=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect, _ := hawk.NewClient(testCredential, opt).Header("GET", "http://example.com/resource?a=1")
+	if h != expect {
+		t.Errorf("unexpected header: actual=%s, expect=%s", h, expect)
+	}
+
+	req, _ := http.NewRequest("GET", "http://example.com/resource?a=1", nil)
+	req.Header.Set("Authorization", h)
+	act, err := s.Authenticate(req)
+	if err != nil {
+		t.Fatalf("return error, %s", err)
+	}
+	if act.ID != testCredential.ID {
+		t.Errorf("unexpected credential: %+v", act)
+	}
+
+	sh, err := s.Header(req, act, &hawk.Option{Ext: "response"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := &http.Response{Header: http.Header{"Server-Authorization": []string{sh}}, Request: req}
+	if ok, err := hawk.NewClient(testCredential, opt).Authenticate(res); !ok {
+		t.Errorf("failed to authenticate server response, %v", err)
+	}
+
+	u, err := BewitURL(cred, "http://example.com/resource?a=1", time.Minute, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	breq, _ := http.NewRequest("GET", u, nil)
+	if _, err := s.AuthenticateBewit(breq); err != nil {
+		t.Errorf("return error, %s", err)
+	}
+
+	if signer.Calls() != 5 {
+		t.Errorf("unexpected calls: %d", signer.Calls())
+	}
+
+	// an unavailable signer
+	signer.Err = errors.New("unavailable")
+	req1, _ := http.NewRequest("GET", "http://example.com/resource?a=1", nil)
+	req1.Header.Set("Authorization", expect)
+	if _, err := s.Authenticate(req1); err == nil || err.Error() != "Failed to calculate MAC." {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := hawk.NewClient(cred, opt).Header("GET", "http://example.com/resource"); err == nil {
+		t.Error("expected an error, but got nil")
+	}
+}
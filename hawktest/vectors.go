@@ -0,0 +1,265 @@
+package hawktest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/hiyosi/hawk"
+)
+
+// Vector types.
+const (
+	VectorHeader   = "header"
+	VectorResponse = "response"
+	VectorBewit    = "bewit"
+	VectorPayload  = "payload"
+	VectorTs       = "ts"
+)
+
+// VectorCredential is the credential of a Vector. Alg is "sha256" or "sha512".
+type VectorCredential struct {
+	ID  string `json:"id,omitempty"`
+	Key string `json:"key,omitempty"`
+	Alg string `json:"alg"`
+}
+
+// Vector is a test vector for Hawk implementations.
+//
+// Normalized is the string covered by the MAC (or the payload hash), and Expected is the base64 encoded
+// MAC (or the payload hash). Header is the expected Authorization or Server-Authorization header value,
+// and Bewit is the expected bewit parameter value, when applicable.
+// Host is the Host header seen by the server, when it differs from the host of URL.
+// For bewit vectors, TimeStamp is the expiration time.
+type Vector struct {
+	Name        string           `json:"name"`
+	Type        string           `json:"type"`
+	Source      string           `json:"source"`
+	Credential  VectorCredential `json:"credential"`
+	Method      string           `json:"method,omitempty"`
+	URL         string           `json:"url,omitempty"`
+	Host        string           `json:"host,omitempty"`
+	TimeStamp   int64            `json:"timestamp,omitempty"`
+	Nonce       string           `json:"nonce,omitempty"`
+	ContentType string           `json:"content_type,omitempty"`
+	Payload     string           `json:"payload,omitempty"`
+	Hash        string           `json:"hash,omitempty"`
+	Ext         string           `json:"ext,omitempty"`
+	App         string           `json:"app,omitempty"`
+	Dlg         string           `json:"dlg,omitempty"`
+	Normalized  string           `json:"normalized"`
+	Expected    string           `json:"expected"`
+	Header      string           `json:"header,omitempty"`
+	Bewit       string           `json:"bewit,omitempty"`
+}
+
+// Vectors returns the test vectors covering header, response, bewit, payload hash and timestamp MACs.
+func Vectors() []Vector {
+	v := make([]Vector, len(vectors))
+	copy(v, vectors)
+	return v
+}
+
+// WriteVectors writes the test vectors as JSON, so that they can be used to check other implementations.
+func WriteVectors(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(vectors)
+}
+
+// ReadVectors reads test vectors written by WriteVectors.
+func ReadVectors(r io.Reader) ([]Vector, error) {
+	var v []Vector
+	if err := json.NewDecoder(r).Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// VerifyVector checks the vector against this package, and returns an error describing the first mismatch.
+func VerifyVector(v Vector) error {
+	cred := &hawk.Credential{ID: v.Credential.ID, Key: v.Credential.Key}
+	if err := cred.Alg.UnmarshalText([]byte(v.Credential.Alg)); err != nil {
+		return fmt.Errorf("unknown alg %q", v.Credential.Alg)
+	}
+
+	switch v.Type {
+	case VectorHeader:
+		return verifyHeader(v, cred)
+	case VectorResponse:
+		return verifyResponse(v, cred)
+	case VectorBewit:
+		return verifyBewit(v, cred)
+	case VectorPayload:
+		ph := &hawk.PayloadHash{ContentType: v.ContentType, Payload: v.Payload, Alg: cred.Alg}
+		return compare(v, ph.Normalized(), ph.String())
+	case VectorTs:
+		tm := &hawk.TsMac{TimeStamp: v.TimeStamp, Credential: cred}
+		return compare(v, tm.Normalized(), tm.String())
+	default:
+		return fmt.Errorf("unknown type %q", v.Type)
+	}
+}
+
+func verifyHeader(v Vector, cred *hawk.Credential) error {
+	opt := &hawk.Option{
+		TimeStamp: v.TimeStamp,
+		Nonce:     v.Nonce,
+		Hash:      v.Hash,
+		Ext:       v.Ext,
+		App:       v.App,
+		Dlg:       v.Dlg,
+	}
+	if err := verifyMac(v, cred, hawk.Header, opt); err != nil {
+		return err
+	}
+	if v.Header == "" {
+		return nil
+	}
+
+	c := hawk.NewClient(cred, &hawk.Option{
+		TimeStamp:   v.TimeStamp,
+		Nonce:       v.Nonce,
+		Ext:         v.Ext,
+		App:         v.App,
+		Dlg:         v.Dlg,
+		ContentType: v.ContentType,
+		Payload:     v.Payload,
+	})
+	// the client signs the request for the host seen by the server.
+	u, err := url.Parse(v.URL)
+	if err != nil {
+		return err
+	}
+	if v.Host != "" {
+		u.Host = v.Host
+	}
+	h, err := c.Header(v.Method, u.String())
+	if err != nil {
+		return err
+	}
+	if h != v.Header {
+		return fmt.Errorf("header mismatch: got %s", h)
+	}
+
+	// the server accepts the header
+	req, err := http.NewRequest(v.Method, v.URL, nil)
+	if err != nil {
+		return err
+	}
+	if v.Host != "" {
+		req.Host = v.Host
+	}
+	req.Header.Set("Authorization", v.Header)
+	req.Header.Set("Content-Type", v.ContentType)
+
+	s := hawk.NewServer(NewCredentialStore(cred))
+	s.AuthOption = &hawk.AuthOption{CustomClock: FixedClock(v.TimeStamp)}
+	s.Payload = v.Payload
+	if _, err := s.Authenticate(req); err != nil {
+		return fmt.Errorf("server rejected the header: %s", err)
+	}
+	return nil
+}
+
+func verifyResponse(v Vector, cred *hawk.Credential) error {
+	opt := &hawk.Option{
+		TimeStamp: v.TimeStamp,
+		Nonce:     v.Nonce,
+		Hash:      v.Hash,
+		Ext:       v.Ext,
+		App:       v.App,
+		Dlg:       v.Dlg,
+	}
+	if err := verifyMac(v, cred, hawk.Response, opt); err != nil {
+		return err
+	}
+	if v.Header == "" {
+		return nil
+	}
+
+	c := hawk.NewClient(cred, &hawk.Option{
+		TimeStamp: v.TimeStamp,
+		Nonce:     v.Nonce,
+		App:       v.App,
+		Dlg:       v.Dlg,
+	})
+	authz, err := c.Header(v.Method, v.URL)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(v.Method, v.URL, nil)
+	if err != nil {
+		return err
+	}
+	if v.Host != "" {
+		req.Host = v.Host
+	}
+	req.Header.Set("Authorization", authz)
+
+	s := hawk.NewServer(NewCredentialStore(cred))
+	h, err := s.Header(req, cred, &hawk.Option{Hash: v.Hash, Ext: v.Ext})
+	if err != nil {
+		return err
+	}
+	if h != v.Header {
+		return fmt.Errorf("header mismatch: got %s", h)
+	}
+	return nil
+}
+
+func verifyBewit(v Vector, cred *hawk.Credential) error {
+	opt := &hawk.Option{
+		TimeStamp: v.TimeStamp,
+		Ext:       v.Ext,
+	}
+	if err := verifyMac(v, cred, hawk.Bewit, opt); err != nil {
+		return err
+	}
+	if v.Bewit == "" {
+		return nil
+	}
+
+	b := hawk.NewBewitConfig(cred, time.Minute)
+	b.Ext = v.Ext
+	bewit := b.GetBewit(v.URL, FixedClock(v.TimeStamp-60))
+	if bewit != v.Bewit {
+		return fmt.Errorf("bewit mismatch: got %s", bewit)
+	}
+	return nil
+}
+
+func verifyMac(v Vector, cred *hawk.Credential, authType hawk.AuthType, opt *hawk.Option) error {
+	m := &hawk.Mac{
+		Type:       authType,
+		Credential: cred,
+		Uri:        v.URL,
+		Method:     v.Method,
+		HostPort:   v.Host,
+		Option:     opt,
+	}
+	ns, err := m.Normalized()
+	if err != nil {
+		return err
+	}
+	mac, err := m.String()
+	if err != nil {
+		return err
+	}
+	return compare(v, ns, mac)
+}
+
+func compare(v Vector, normalized, mac string) error {
+	if normalized != v.Normalized {
+		return fmt.Errorf("normalized string mismatch: got %q", normalized)
+	}
+	if mac != v.Expected {
+		return errors.New("mac mismatch: got " + mac)
+	}
+	return nil
+}
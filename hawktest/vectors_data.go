@@ -0,0 +1,358 @@
+package hawktest
+
+// vectors are computed with an independent implementation of the Hawk specification.
+// The ones with "hawk-spec" source are the examples of the specification itself.
+var vectors = []Vector{
+	{
+		Name:       "spec header",
+		Type:       "header",
+		Source:     "hawk-spec",
+		Credential: VectorCredential{ID: "dh37fgj492je", Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn", Alg: "sha256"},
+		Method:     "GET",
+		URL:        "http://example.com:8000/resource/1?b=1&a=2",
+		TimeStamp:  1353832234,
+		Nonce:      "j4h3g2",
+		Ext:        "some-app-ext-data",
+		Normalized: "hawk.1.header\n1353832234\nj4h3g2\nGET\n/resource/1?b=1&a=2\nexample.com\n8000\n\nsome-app-ext-data\n",
+		Expected:   "6R4rV5iE+NPoym+WwjeHzjAGXUtLNIxmo1vpMofpLAE=",
+		Header:     `Hawk id="dh37fgj492je", ts="1353832234", nonce="j4h3g2", ext="some-app-ext-data", mac="6R4rV5iE+NPoym+WwjeHzjAGXUtLNIxmo1vpMofpLAE="`,
+	},
+	{
+		Name:        "spec header with payload",
+		Type:        "header",
+		Source:      "hawk-spec",
+		Credential:  VectorCredential{ID: "dh37fgj492je", Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn", Alg: "sha256"},
+		Method:      "POST",
+		URL:         "http://example.com:8000/resource/1?b=1&a=2",
+		TimeStamp:   1353832234,
+		Nonce:       "j4h3g2",
+		ContentType: "text/plain",
+		Payload:     "Thank you for flying Hawk",
+		Hash:        "Yi9LfIIFRtBEPt74PVmbTF/xVAwPn7ub15ePICfgnuY=",
+		Ext:         "some-app-ext-data",
+		Normalized:  "hawk.1.header\n1353832234\nj4h3g2\nPOST\n/resource/1?b=1&a=2\nexample.com\n8000\nYi9LfIIFRtBEPt74PVmbTF/xVAwPn7ub15ePICfgnuY=\nsome-app-ext-data\n",
+		Expected:    "aSe1DERmZuRl3pI36/9BdZmnErTw3sNzOOAUlfeKjVw=",
+		Header:      `Hawk id="dh37fgj492je", ts="1353832234", nonce="j4h3g2", hash="Yi9LfIIFRtBEPt74PVmbTF/xVAwPn7ub15ePICfgnuY=", ext="some-app-ext-data", mac="aSe1DERmZuRl3pI36/9BdZmnErTw3sNzOOAUlfeKjVw="`,
+	},
+	{
+		Name:       "default http port",
+		Type:       "header",
+		Source:     "generated",
+		Credential: VectorCredential{ID: "dh37fgj492je", Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn", Alg: "sha256"},
+		Method:     "GET",
+		URL:        "http://example.com/resource/1",
+		TimeStamp:  1353832234,
+		Nonce:      "j4h3g2",
+		Normalized: "hawk.1.header\n1353832234\nj4h3g2\nGET\n/resource/1\nexample.com\n80\n\n\n",
+		Expected:   "sDH4748rKN/lqMv08IvTKy8NwJ9nbOPX8+CUrOIyRGs=",
+		Header:     `Hawk id="dh37fgj492je", ts="1353832234", nonce="j4h3g2", mac="sDH4748rKN/lqMv08IvTKy8NwJ9nbOPX8+CUrOIyRGs="`,
+	},
+	{
+		Name:       "default https port",
+		Type:       "header",
+		Source:     "generated",
+		Credential: VectorCredential{ID: "dh37fgj492je", Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn", Alg: "sha256"},
+		Method:     "GET",
+		URL:        "https://example.com/resource/1",
+		TimeStamp:  1353832234,
+		Nonce:      "j4h3g2",
+		Normalized: "hawk.1.header\n1353832234\nj4h3g2\nGET\n/resource/1\nexample.com\n443\n\n\n",
+		Expected:   "zhxc6Lp4A+53C5t1yjfeIxHBiTm6uZ52oAfF3zFNRnw=",
+		Header:     `Hawk id="dh37fgj492je", ts="1353832234", nonce="j4h3g2", mac="zhxc6Lp4A+53C5t1yjfeIxHBiTm6uZ52oAfF3zFNRnw="`,
+	},
+	{
+		Name:       "explicit default port",
+		Type:       "header",
+		Source:     "generated",
+		Credential: VectorCredential{ID: "dh37fgj492je", Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn", Alg: "sha256"},
+		Method:     "GET",
+		URL:        "https://example.com:443/resource/1",
+		TimeStamp:  1353832234,
+		Nonce:      "j4h3g2",
+		Normalized: "hawk.1.header\n1353832234\nj4h3g2\nGET\n/resource/1\nexample.com\n443\n\n\n",
+		Expected:   "zhxc6Lp4A+53C5t1yjfeIxHBiTm6uZ52oAfF3zFNRnw=",
+		Header:     `Hawk id="dh37fgj492je", ts="1353832234", nonce="j4h3g2", mac="zhxc6Lp4A+53C5t1yjfeIxHBiTm6uZ52oAfF3zFNRnw="`,
+	},
+	{
+		Name:       "upper-case host and lower-case method",
+		Type:       "header",
+		Source:     "generated",
+		Credential: VectorCredential{ID: "dh37fgj492je", Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn", Alg: "sha256"},
+		Method:     "post",
+		URL:        "http://EXAMPLE.com:8080/resource",
+		TimeStamp:  1353832234,
+		Nonce:      "j4h3g2",
+		Normalized: "hawk.1.header\n1353832234\nj4h3g2\nPOST\n/resource\nexample.com\n8080\n\n\n",
+		Expected:   "MBdBUdEx5zwvgMlAwHOFdc07ebVGNv9cRLPz27owDAw=",
+		Header:     `Hawk id="dh37fgj492je", ts="1353832234", nonce="j4h3g2", mac="MBdBUdEx5zwvgMlAwHOFdc07ebVGNv9cRLPz27owDAw="`,
+	},
+	{
+		Name:       "encoded path",
+		Type:       "header",
+		Source:     "generated",
+		Credential: VectorCredential{ID: "dh37fgj492je", Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn", Alg: "sha256"},
+		Method:     "GET",
+		URL:        "http://example.com:8000/resource/x%2Fy%2Fz?b=1&a=2",
+		TimeStamp:  1353832234,
+		Nonce:      "j4h3g2",
+		Ext:        "some-app-ext-data",
+		Normalized: "hawk.1.header\n1353832234\nj4h3g2\nGET\n/resource/x%2Fy%2Fz?b=1&a=2\nexample.com\n8000\n\nsome-app-ext-data\n",
+		Expected:   "nurs0/PPVGhFt9v2gzBP4BCRQwzQJwPuIQKLYjoVIQ0=",
+		Header:     `Hawk id="dh37fgj492je", ts="1353832234", nonce="j4h3g2", ext="some-app-ext-data", mac="nurs0/PPVGhFt9v2gzBP4BCRQwzQJwPuIQKLYjoVIQ0="`,
+	},
+	{
+		Name:       "repeated and encoded query",
+		Type:       "header",
+		Source:     "generated",
+		Credential: VectorCredential{ID: "dh37fgj492je", Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn", Alg: "sha256"},
+		Method:     "GET",
+		URL:        "http://example.com/search?q=a%20b&q=c+d&z=%E3%81%82&a",
+		TimeStamp:  1353832234,
+		Nonce:      "j4h3g2",
+		Normalized: "hawk.1.header\n1353832234\nj4h3g2\nGET\n/search?q=a%20b&q=c+d&z=%E3%81%82&a\nexample.com\n80\n\n\n",
+		Expected:   "EkUyvVM/5ICjip2TU5/vPDJXtF0w6AojVgNHOzPcL4U=",
+		Header:     `Hawk id="dh37fgj492je", ts="1353832234", nonce="j4h3g2", mac="EkUyvVM/5ICjip2TU5/vPDJXtF0w6AojVgNHOzPcL4U="`,
+	},
+	{
+		Name:       "empty path",
+		Type:       "header",
+		Source:     "generated",
+		Credential: VectorCredential{ID: "dh37fgj492je", Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn", Alg: "sha256"},
+		Method:     "GET",
+		URL:        "http://example.com",
+		TimeStamp:  1353832234,
+		Nonce:      "j4h3g2",
+		Normalized: "hawk.1.header\n1353832234\nj4h3g2\nGET\n/\nexample.com\n80\n\n\n",
+		Expected:   "en2WpkVTF2+9Fhjo7NKFuu1ytCDK9L/7Sp64tTin5ao=",
+		Header:     `Hawk id="dh37fgj492je", ts="1353832234", nonce="j4h3g2", mac="en2WpkVTF2+9Fhjo7NKFuu1ytCDK9L/7Sp64tTin5ao="`,
+	},
+	{
+		Name:       "custom host",
+		Type:       "header",
+		Source:     "generated",
+		Credential: VectorCredential{ID: "dh37fgj492je", Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn", Alg: "sha256"},
+		Method:     "GET",
+		URL:        "http://internal/resource/1?b=1&a=2",
+		Host:       "example.com:8000",
+		TimeStamp:  1353832234,
+		Nonce:      "j4h3g2",
+		Normalized: "hawk.1.header\n1353832234\nj4h3g2\nGET\n/resource/1?b=1&a=2\nexample.com\n8000\n\n\n",
+		Expected:   "nfp3t5BVkMvjhU3PrD0ftTp7NcVpETEX2HEi/Fo4S2g=",
+		Header:     `Hawk id="dh37fgj492je", ts="1353832234", nonce="j4h3g2", mac="nfp3t5BVkMvjhU3PrD0ftTp7NcVpETEX2HEi/Fo4S2g="`,
+	},
+	{
+		Name:       "ext escaping",
+		Type:       "header",
+		Source:     "generated",
+		Credential: VectorCredential{ID: "dh37fgj492je", Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn", Alg: "sha256"},
+		Method:     "GET",
+		URL:        "http://example.com/resource",
+		TimeStamp:  1353832234,
+		Nonce:      "j4h3g2",
+		Ext:        "a\\b\nc",
+		Normalized: "hawk.1.header\n1353832234\nj4h3g2\nGET\n/resource\nexample.com\n80\n\na\\\\b\\nc\n",
+		Expected:   "iLKnOSiGXRFXvmAIETe3d5fBNOhq34E6VjysRbh3NCs=",
+	},
+	{
+		Name:       "app and dlg",
+		Type:       "header",
+		Source:     "generated",
+		Credential: VectorCredential{ID: "dh37fgj492je", Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn", Alg: "sha256"},
+		Method:     "GET",
+		URL:        "http://example.com:8080/resource/1?b=1&a=2",
+		TimeStamp:  1353832234,
+		Nonce:      "j4h3g2",
+		Ext:        "some-app-ext-data",
+		App:        "some-app-id",
+		Dlg:        "some-dlg",
+		Normalized: "hawk.1.header\n1353832234\nj4h3g2\nGET\n/resource/1?b=1&a=2\nexample.com\n8080\n\nsome-app-ext-data\nsome-app-id\nsome-dlg\n",
+		Expected:   "3glACULyTDnGSBEBpkFbRxRTFSXauan/Jk7NpA1MKl0=",
+		Header:     `Hawk id="dh37fgj492je", ts="1353832234", nonce="j4h3g2", ext="some-app-ext-data", mac="3glACULyTDnGSBEBpkFbRxRTFSXauan/Jk7NpA1MKl0=", app="some-app-id", dlg="some-dlg"`,
+	},
+	{
+		Name:       "app without dlg",
+		Type:       "header",
+		Source:     "generated",
+		Credential: VectorCredential{ID: "dh37fgj492je", Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn", Alg: "sha256"},
+		Method:     "GET",
+		URL:        "http://example.com:8080/resource/1",
+		TimeStamp:  1353832234,
+		Nonce:      "j4h3g2",
+		App:        "some-app-id",
+		Normalized: "hawk.1.header\n1353832234\nj4h3g2\nGET\n/resource/1\nexample.com\n8080\n\n\nsome-app-id\n\n",
+		Expected:   "Fp0qCf7NPRMkAoC21Dub7I/yeay8rSzd6nDt3NTCRCY=",
+		Header:     `Hawk id="dh37fgj492je", ts="1353832234", nonce="j4h3g2", mac="Fp0qCf7NPRMkAoC21Dub7I/yeay8rSzd6nDt3NTCRCY=", app="some-app-id"`,
+	},
+	{
+		Name:       "sha512",
+		Type:       "header",
+		Source:     "generated",
+		Credential: VectorCredential{ID: "dh37fgj492je", Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn", Alg: "sha512"},
+		Method:     "GET",
+		URL:        "http://example.com:8000/resource/1?b=1&a=2",
+		TimeStamp:  1353832234,
+		Nonce:      "j4h3g2",
+		Ext:        "some-app-ext-data",
+		Normalized: "hawk.1.header\n1353832234\nj4h3g2\nGET\n/resource/1?b=1&a=2\nexample.com\n8000\n\nsome-app-ext-data\n",
+		Expected:   "EcTUjNOUn8AC7Lz+SkQy2mBv2tbeA+mgMjpYC1M3X3DEBwYcmEIvwnPHdVd1dBhSO+Y63tb+kImNnt2ZV8PXfg==",
+		Header:     `Hawk id="dh37fgj492je", ts="1353832234", nonce="j4h3g2", ext="some-app-ext-data", mac="EcTUjNOUn8AC7Lz+SkQy2mBv2tbeA+mgMjpYC1M3X3DEBwYcmEIvwnPHdVd1dBhSO+Y63tb+kImNnt2ZV8PXfg=="`,
+	},
+	{
+		Name:        "sha512 with payload",
+		Type:        "header",
+		Source:      "generated",
+		Credential:  VectorCredential{ID: "dh37fgj492je", Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn", Alg: "sha512"},
+		Method:      "PUT",
+		URL:         "https://example.com/resource/1",
+		TimeStamp:   1353832234,
+		Nonce:       "j4h3g2",
+		ContentType: "application/json; charset=utf-8",
+		Payload:     "{\"a\":1}",
+		Hash:        "El5bfOMztifTW22UugjvWwCl+Xz2wEfP93FFnes6XaJQcuekXS7zo+pRhOu0o7zZK4hHCtfH4m+WlObsi47yrA==",
+		Normalized:  "hawk.1.header\n1353832234\nj4h3g2\nPUT\n/resource/1\nexample.com\n443\nEl5bfOMztifTW22UugjvWwCl+Xz2wEfP93FFnes6XaJQcuekXS7zo+pRhOu0o7zZK4hHCtfH4m+WlObsi47yrA==\n\n",
+		Expected:    "nmdZ7qYMK/BJQsGQRNZj3otPl/I2LWz1R6OirHbYr6gi2n5rpzbJXOnzqOyqa4juR1JojGH7DTrQr8THT6TTHA==",
+		Header:      `Hawk id="dh37fgj492je", ts="1353832234", nonce="j4h3g2", hash="El5bfOMztifTW22UugjvWwCl+Xz2wEfP93FFnes6XaJQcuekXS7zo+pRhOu0o7zZK4hHCtfH4m+WlObsi47yrA==", mac="nmdZ7qYMK/BJQsGQRNZj3otPl/I2LWz1R6OirHbYr6gi2n5rpzbJXOnzqOyqa4juR1JojGH7DTrQr8THT6TTHA=="`,
+	},
+	{
+		Name:       "response",
+		Type:       "response",
+		Source:     "generated",
+		Credential: VectorCredential{ID: "dh37fgj492je", Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn", Alg: "sha256"},
+		Method:     "GET",
+		URL:        "http://example.com:8000/resource/1?b=1&a=2",
+		TimeStamp:  1353832234,
+		Nonce:      "j4h3g2",
+		Ext:        "response-specific",
+		Normalized: "hawk.1.response\n1353832234\nj4h3g2\nGET\n/resource/1?b=1&a=2\nexample.com\n8000\n\nresponse-specific\n",
+		Expected:   "xY6dN3Hws9o+XRICYnAcuxFOPLd1BZ7BkkJhUSpPidA=",
+		Header:     `Hawk mac="xY6dN3Hws9o+XRICYnAcuxFOPLd1BZ7BkkJhUSpPidA=", ext="response-specific"`,
+	},
+	{
+		Name:        "response with payload",
+		Type:        "response",
+		Source:      "generated",
+		Credential:  VectorCredential{ID: "dh37fgj492je", Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn", Alg: "sha256"},
+		Method:      "POST",
+		URL:         "http://example.com:8080/resource/4?filter=a",
+		TimeStamp:   1398546787,
+		Nonce:       "xUwusx",
+		ContentType: "text/plain",
+		Payload:     "some reply",
+		Hash:        "f9cDF/TDm7TkYRLnGwRMfeDzT6LixQVLvrIKhh0vgmM=",
+		Ext:         "response-specific",
+		Normalized:  "hawk.1.response\n1398546787\nxUwusx\nPOST\n/resource/4?filter=a\nexample.com\n8080\nf9cDF/TDm7TkYRLnGwRMfeDzT6LixQVLvrIKhh0vgmM=\nresponse-specific\n",
+		Expected:    "n14wVJK4cOxAytPUMc5bPezQzuJGl5n7MYXhFQgEKsE=",
+		Header:      `Hawk mac="n14wVJK4cOxAytPUMc5bPezQzuJGl5n7MYXhFQgEKsE=", hash="f9cDF/TDm7TkYRLnGwRMfeDzT6LixQVLvrIKhh0vgmM=", ext="response-specific"`,
+	},
+	{
+		Name:       "response with app",
+		Type:       "response",
+		Source:     "generated",
+		Credential: VectorCredential{ID: "dh37fgj492je", Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn", Alg: "sha256"},
+		Method:     "GET",
+		URL:        "https://example.com/resource",
+		TimeStamp:  1398546787,
+		Nonce:      "xUwusx",
+		App:        "some-app-id",
+		Dlg:        "some-dlg",
+		Normalized: "hawk.1.response\n1398546787\nxUwusx\nGET\n/resource\nexample.com\n443\n\n\nsome-app-id\nsome-dlg\n",
+		Expected:   "Ua6AsD1qfXWGmcuNHsOAsUdtJlpYnpOOD1BOkKnOhF4=",
+		Header:     `Hawk mac="Ua6AsD1qfXWGmcuNHsOAsUdtJlpYnpOOD1BOkKnOhF4="`,
+	},
+	{
+		Name:       "bewit",
+		Type:       "bewit",
+		Source:     "hawk-spec",
+		Credential: VectorCredential{ID: "123456", Key: "2983d45yun89q", Alg: "sha256"},
+		Method:     "GET",
+		URL:        "http://example.com/resource/4?a=1&b=2",
+		TimeStamp:  4519311458,
+		Ext:        "some-app-data",
+		Normalized: "hawk.1.bewit\n4519311458\n\nGET\n/resource/4?a=1&b=2\nexample.com\n80\n\nsome-app-data\n",
+		Expected:   "bI0jqeKZkPq4WXQ2i1+CkCiNjvDspRVCFj9flIj1zaY=",
+		Bewit:      "MTIzNDU2XDQ1MTkzMTE0NThcYkkwanFlS1prUHE0V1hRMmkxK0NrQ2lOanZEc3BSVkNGajlmbElqMXphWT1cc29tZS1hcHAtZGF0YQ",
+	},
+	{
+		Name:       "bewit without ext",
+		Type:       "bewit",
+		Source:     "generated",
+		Credential: VectorCredential{ID: "123456", Key: "2983d45yun89q", Alg: "sha256"},
+		Method:     "GET",
+		URL:        "https://example.com/resource/4",
+		TimeStamp:  1365711518,
+		Normalized: "hawk.1.bewit\n1365711518\n\nGET\n/resource/4\nexample.com\n443\n\n\n",
+		Expected:   "WyvsGaA/sOCru5immPNcaco4s+oUfc3AYO0gHwNtJYI=",
+		Bewit:      "MTIzNDU2XDEzNjU3MTE1MThcV3l2c0dhQS9zT0NydTVpbW1QTmNhY280cytvVWZjM0FZTzBnSHdOdEpZST1c",
+	},
+	{
+		Name:       "bewit with escaped ext",
+		Type:       "bewit",
+		Source:     "generated",
+		Credential: VectorCredential{ID: "123456", Key: "2983d45yun89q", Alg: "sha256"},
+		Method:     "GET",
+		URL:        "http://example.com:8080/download?file=a%2Fb",
+		TimeStamp:  1365711518,
+		Ext:        "x\ny",
+		Normalized: "hawk.1.bewit\n1365711518\n\nGET\n/download?file=a%2Fb\nexample.com\n8080\n\nx\\ny\n",
+		Expected:   "/c6QMVATS7MsQnYd3OPvSlOlN4BOfIvijZsFRhNrC0w=",
+		Bewit:      "MTIzNDU2XDEzNjU3MTE1MThcL2M2UU1WQVRTN01zUW5ZZDNPUHZTbE9sTjRCT2ZJdmlqWnNGUmhOckMwdz1ceAp5",
+	},
+	{
+		Name:        "spec payload",
+		Type:        "payload",
+		Source:      "hawk-spec",
+		Credential:  VectorCredential{Alg: "sha256"},
+		ContentType: "text/plain",
+		Payload:     "Thank you for flying Hawk",
+		Normalized:  "hawk.1.payload\ntext/plain\nThank you for flying Hawk\n",
+		Expected:    "Yi9LfIIFRtBEPt74PVmbTF/xVAwPn7ub15ePICfgnuY=",
+	},
+	{
+		Name:        "content type parameters",
+		Type:        "payload",
+		Source:      "generated",
+		Credential:  VectorCredential{Alg: "sha256"},
+		ContentType: "Text/Plain; charset=utf-8",
+		Payload:     "Thank you for flying Hawk",
+		Normalized:  "hawk.1.payload\ntext/plain\nThank you for flying Hawk\n",
+		Expected:    "Yi9LfIIFRtBEPt74PVmbTF/xVAwPn7ub15ePICfgnuY=",
+	},
+	{
+		Name:        "empty payload",
+		Type:        "payload",
+		Source:      "generated",
+		Credential:  VectorCredential{Alg: "sha256"},
+		ContentType: "",
+		Payload:     "",
+		Normalized:  "hawk.1.payload\n\n\n",
+		Expected:    "B0weSUXsMcb5UhL41FZbrUJCAotzSI3HawE1NPLRUz8=",
+	},
+	{
+		Name:        "multi-line payload",
+		Type:        "payload",
+		Source:      "generated",
+		Credential:  VectorCredential{Alg: "sha512"},
+		ContentType: "application/json",
+		Payload:     "{\n  \"a\": \"\\u3042\"\n}",
+		Normalized:  "hawk.1.payload\napplication/json\n{\n  \"a\": \"\\u3042\"\n}\n",
+		Expected:    "VNGkuFax5YAvdD5QQPZlzfZlElWFOaoZWKP/hPdSKdF14MdSg9w90H7UL7BdfPJ1JDhYra3PvPl1viGF6TSYzg==",
+	},
+	{
+		Name:       "ts challenge",
+		Type:       "ts",
+		Source:     "generated",
+		Credential: VectorCredential{ID: "123456", Key: "2983d45yun89q", Alg: "sha256"},
+		TimeStamp:  1365741469,
+		Normalized: "hawk.1.ts\n1365741469\n",
+		Expected:   "h/Ff6XI1euObD78ZNflapvLKXGuaw1RiLI4Q6Q5sAbM=",
+	},
+	{
+		Name:       "ts challenge sha512",
+		Type:       "ts",
+		Source:     "generated",
+		Credential: VectorCredential{ID: "dh37fgj492je", Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn", Alg: "sha512"},
+		TimeStamp:  1365741469,
+		Normalized: "hawk.1.ts\n1365741469\n",
+		Expected:   "Ov/FFnzuzSHWwPpCg3ZMiADno6FQeo5PjVp9ehsLc7EGmv7DMbROMXLYk7YujAv0EHFEtxHoHwbCkqHY1k7clg==",
+	},
+}
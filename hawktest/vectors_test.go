@@ -0,0 +1,65 @@
+package hawktest
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update testdata/vectors.json")
+
+func TestVectors(t *testing.T) {
+	for _, v := range Vectors() {
+		t.Run(v.Type+"/"+v.Name, func(t *testing.T) {
+			if err := VerifyVector(v); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestWriteVectors(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteVectors(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if *update {
+		if err := ioutil.WriteFile("testdata/vectors.json", buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the exported file is kept in sync with the vectors.
+	expect, err := ioutil.ReadFile("testdata/vectors.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), expect) {
+		t.Error("testdata/vectors.json is outdated, run go test with -update")
+	}
+
+	v, err := ReadVectors(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(v, Vectors()) {
+		t.Error("vectors are not round-tripped")
+	}
+}
+
+func TestVerifyVector_Mismatch(t *testing.T) {
+	v := Vectors()[0]
+	v.Expected = "AAAA"
+	if err := VerifyVector(v); err == nil {
+		t.Error("expected an error, but got nil")
+	}
+
+	v = Vectors()[0]
+	v.Normalized = "hawk.1.header\n"
+	if err := VerifyVector(v); err == nil {
+		t.Error("expected an error, but got nil")
+	}
+}
@@ -0,0 +1,136 @@
+package hawk
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"math"
+	"strconv"
+)
+
+// CredentialGenerator generates credentials with random keys.
+type CredentialGenerator struct {
+	Alg Alg
+	// IDPrefix is prepended to the random part of the id, e.g. "partner-".
+	IDPrefix string
+	// IDLength is the number of random bytes of the id, which are hex encoded. The default is 8.
+	IDLength int
+	// NewID, if set, is used instead of IDPrefix and IDLength, e.g. to use the ids of an existing system.
+	NewID func() (string, error)
+	// KeyLength is the number of random bytes of the key, which are base64url encoded.
+	// The default is the output size of the hash of Alg, 32 for SHA256 and 64 for SHA512.
+	KeyLength int
+}
+
+// NewCredentialGenerator initializes a new CredentialGenerator with the default lengths.
+func NewCredentialGenerator(alg Alg) *CredentialGenerator {
+	return &CredentialGenerator{
+		Alg: alg,
+	}
+}
+
+// Generate returns a new credential.
+func (g *CredentialGenerator) Generate() (*Credential, error) {
+	if g.Alg != SHA256 && g.Alg != SHA512 {
+		return nil, errors.New("Invalid alg.")
+	}
+
+	var id string
+	var err error
+	if g.NewID != nil {
+		id, err = g.NewID()
+	} else {
+		n := g.IDLength
+		if n <= 0 {
+			n = 8
+		}
+		id, err = randomString(n, hex.EncodeToString)
+		id = g.IDPrefix + id
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	n := g.KeyLength
+	if n <= 0 {
+		n = getHash(g.Alg)().Size()
+	}
+	key, err := randomString(n, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Credential{
+		ID:  id,
+		Key: key,
+		Alg: g.Alg,
+	}, nil
+}
+
+func randomString(n int, encode func([]byte) string) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return encode(b), nil
+}
+
+// KeyPolicy rejects weak keys.
+type KeyPolicy struct {
+	// MinLength is the minimum length of a key in bytes.
+	MinLength int
+	// MinEntropy is the minimum entropy of a key in bits, estimated from the frequency of its bytes.
+	// It catches keys like "aaaa...", or a password repeated several times.
+	MinEntropy float64
+}
+
+// DefaultKeyPolicy accepts keys generated by CredentialGenerator with the default lengths.
+var DefaultKeyPolicy = &KeyPolicy{
+	MinLength:  32,
+	MinEntropy: 128,
+}
+
+// Validate returns an error if the key of the credential is weak, or the alg is invalid.
+func (p *KeyPolicy) Validate(cred *Credential) error {
+	if cred.Alg != SHA256 && cred.Alg != SHA512 {
+		return errors.New("Invalid alg.")
+	}
+	if len(cred.Key) < p.MinLength {
+		return errors.New("Key too short, at least " + strconv.Itoa(p.MinLength) + " bytes are required.")
+	}
+	if keyEntropy(cred.Key) < p.MinEntropy {
+		return errors.New("Key too weak.")
+	}
+	return nil
+}
+
+// keyEntropy estimates the entropy of the key in bits: the Shannon entropy of its bytes,
+// times the length of the key without repetitions.
+func keyEntropy(key string) float64 {
+	var counts [256]int
+	for i := 0; i < len(key); i++ {
+		counts[key[i]]++
+	}
+	n := float64(len(key))
+	h := 0.0
+	for _, c := range counts {
+		if c == 0 {
+			continue
+		}
+		p := float64(c) / n
+		h -= p * math.Log2(p)
+	}
+	return h * float64(keyPeriod(key))
+}
+
+// keyPeriod returns the length of the shortest string which repeated makes the key,
+// e.g. 8 for "passwordpasswordpass".
+func keyPeriod(key string) int {
+	for p := 1; p < len(key); p++ {
+		if key[p:] == key[:len(key)-p] {
+			return p
+		}
+	}
+	return len(key)
+}
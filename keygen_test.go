@@ -0,0 +1,83 @@
+package hawk
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCredentialGenerator_Generate(t *testing.T) {
+	for _, tc := range []struct {
+		g         *CredentialGenerator
+		idPrefix  string
+		idLength  int
+		keyLength int
+	}{
+		{g: NewCredentialGenerator(SHA256), idLength: 16, keyLength: 43},
+		{g: NewCredentialGenerator(SHA512), idLength: 16, keyLength: 86},
+		{g: &CredentialGenerator{Alg: SHA256, IDPrefix: "partner-", IDLength: 4, KeyLength: 48}, idPrefix: "partner-", idLength: 16, keyLength: 64},
+		{g: &CredentialGenerator{Alg: SHA256, NewID: func() (string, error) { return "fixed", nil }}, idLength: 5, keyLength: 43},
+	} {
+		c, err := tc.g.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(c.ID, tc.idPrefix) || len(c.ID) != tc.idLength {
+			t.Errorf("unexpected id: %s", c.ID)
+		}
+		if len(c.Key) != tc.keyLength || c.Alg != tc.g.Alg {
+			t.Errorf("unexpected credential: %+v", c)
+		}
+		if err := DefaultKeyPolicy.Validate(c); err != nil {
+			t.Errorf("generated key is rejected, %s", err)
+		}
+
+		c1, _ := tc.g.Generate()
+		if c1.Key == c.Key {
+			t.Error("generated the same key twice")
+		}
+	}
+
+	if _, err := (&CredentialGenerator{}).Generate(); err == nil {
+		t.Error("expected an error for invalid alg, but got nil")
+	}
+}
+
+func TestKeyPolicy_Validate(t *testing.T) {
+	for _, tc := range []struct {
+		key   string
+		alg   Alg
+		valid bool
+	}{
+		{key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn", alg: SHA256, valid: true},
+		{key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn", alg: SHA512, valid: true},
+		{key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn", alg: 0, valid: false},
+		{key: "test-key", alg: SHA256, valid: false},
+		{key: strings.Repeat("a", 64), alg: SHA256, valid: false},
+		{key: strings.Repeat("password", 8), alg: SHA256, valid: false},
+	} {
+		err := DefaultKeyPolicy.Validate(&Credential{Key: tc.key, Alg: tc.alg})
+		if (err == nil) != tc.valid {
+			t.Errorf("unexpected result for %q: %v", tc.key, err)
+		}
+	}
+}
+
+func TestServer_KeyPolicy(t *testing.T) {
+	cred := &Credential{ID: "123456", Key: "test-key", Alg: SHA256}
+	c := NewClient(cred, &Option{TimeStamp: time.Now().Unix(), Nonce: "3hOHpR"})
+	h, _ := c.Header("GET", "http://example.com/resource/1")
+	r, _ := http.NewRequest("GET", "http://example.com/resource/1", nil)
+	r.Header.Set("Authorization", h)
+
+	s := NewServer(&staticCredentialStore{cred: cred})
+	if _, err := s.Authenticate(r); err != nil {
+		t.Errorf("return error, %s", err)
+	}
+
+	s.KeyPolicy = DefaultKeyPolicy
+	if _, err := s.Authenticate(r); err == nil || err.Error() != "Bad MAC" {
+		t.Errorf("expected Bad MAC, but got %v", err)
+	}
+}
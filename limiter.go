@@ -0,0 +1,239 @@
+package hawk
+
+import (
+	"container/list"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Limiter throttles authentication attempts after repeated failures.
+// Keys passed to a Limiter are prefixed with their kind, e.g. "id:dh37fgj492je" or "addr:192.0.2.1".
+// Implementations backed by a shared store (e.g. Redis) allow a cluster of servers to share the state.
+type Limiter interface {
+	// Allow reports whether an attempt for the key may proceed.
+	// If not, it also returns how long the caller should wait before retrying.
+	Allow(key string) (bool, time.Duration)
+	// Failure records a failed attempt for the key.
+	Failure(key string)
+}
+
+// LockedOutError is returned by Server when the Limiter rejects an attempt.
+type LockedOutError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedOutError) Error() string {
+	return "Too many failed attempts."
+}
+
+// RetryAfterHeader returns a value to be set in the Retry-After header.
+func (e *LockedOutError) RetryAfterHeader() string {
+	sec := int64((e.RetryAfter + time.Second - 1) / time.Second)
+	if sec < 1 {
+		sec = 1
+	}
+	return strconv.FormatInt(sec, 10)
+}
+
+// DefaultLimiterEntries is the number of keys a MemoryLimiter tracks when MaxEntries is not set.
+const DefaultLimiterEntries = 100000
+
+// MemoryLimiter is an in-memory Limiter.
+//
+// Each key counts its failures, and one failure is forgiven every Decay.
+// If Decay <= 0, failures are not forgiven and only a lockout clears them.
+// When the count reaches MaxFailures, the key is locked out for Lockout.
+//
+// As the keys are chosen by the clients, at most MaxEntries keys are tracked:
+// when the limiter is full, the key whose last failure is the oldest is forgotten, unless it is locked out.
+// If every key is locked out, the failures of new keys are not recorded until a lockout expires.
+type MemoryLimiter struct {
+	MaxFailures int
+	Decay       time.Duration
+	Lockout     time.Duration
+	// MaxEntries is the maximum number of tracked keys. If 0, DefaultLimiterEntries is used.
+	MaxEntries int
+
+	mu      sync.Mutex
+	entries map[string]*limiterEntry
+	// failing holds the entries which are not locked out, the most recent failure first,
+	// and locked the entries which are locked out, in the order of their lockout.
+	failing   *list.List
+	locked    *list.List
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type limiterEntry struct {
+	key         string
+	failures    int
+	updated     time.Time
+	lockedUntil time.Time
+	locked      bool
+	elem        *list.Element
+}
+
+// NewMemoryLimiter initializes a new MemoryLimiter.
+func NewMemoryLimiter(maxFailures int, decay, lockout time.Duration) *MemoryLimiter {
+	return &MemoryLimiter{
+		MaxFailures: maxFailures,
+		Decay:       decay,
+		Lockout:     lockout,
+	}
+}
+
+func (l *MemoryLimiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e, ok := l.entries[key]
+	if !ok {
+		return true, 0
+	}
+	now := l.clock()
+	if now.Before(e.lockedUntil) {
+		return false, e.lockedUntil.Sub(now)
+	}
+	return true, 0
+}
+
+func (l *MemoryLimiter) Failure(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.clock()
+	if l.entries == nil {
+		l.entries = make(map[string]*limiterEntry)
+		l.failing = list.New()
+		l.locked = list.New()
+	}
+	l.sweep(now)
+
+	e, ok := l.entries[key]
+	if !ok {
+		if len(l.entries) >= l.maxEntries() && !l.evict(now) {
+			return
+		}
+		e = &limiterEntry{key: key, updated: now}
+		e.elem = l.failing.PushFront(e)
+		l.entries[key] = e
+	}
+	if now.Before(e.lockedUntil) {
+		return
+	}
+
+	if e.locked {
+		l.locked.Remove(e.elem)
+		e.locked = false
+		e.elem = l.failing.PushFront(e)
+	} else {
+		l.failing.MoveToFront(e.elem)
+	}
+	l.decay(e, now)
+	e.failures++
+	if l.MaxFailures > 0 && e.failures >= l.MaxFailures {
+		e.lockedUntil = now.Add(l.Lockout)
+		e.failures = 0
+		l.failing.Remove(e.elem)
+		e.locked = true
+		e.elem = l.locked.PushBack(e)
+	}
+}
+
+// decay forgives one failure for every elapsed Decay.
+func (l *MemoryLimiter) decay(e *limiterEntry, now time.Time) {
+	if e.failures == 0 {
+		e.updated = now
+		return
+	}
+	if l.Decay <= 0 {
+		return
+	}
+	n := now.Sub(e.updated) / l.Decay
+	if int64(n) >= int64(e.failures) {
+		e.failures = 0
+		e.updated = now
+		return
+	}
+	e.failures -= int(n)
+	e.updated = e.updated.Add(n * l.Decay)
+}
+
+// sweep removes the entries which are neither locked nor have remaining failures.
+// It runs at most once per Decay or Lockout.
+func (l *MemoryLimiter) sweep(now time.Time) {
+	interval := l.Decay
+	if l.Lockout > interval {
+		interval = l.Lockout
+	}
+	if now.Sub(l.lastSweep) < interval {
+		return
+	}
+	l.lastSweep = now
+
+	for _, e := range l.entries {
+		l.decay(e, now)
+		if !now.Before(e.lockedUntil) && e.failures == 0 {
+			l.remove(e)
+		}
+	}
+}
+
+// evict makes room for a new entry in constant time, and reports whether it did:
+// it forgets the oldest lockout if it has expired, or else the oldest failure of a key which is not locked out.
+func (l *MemoryLimiter) evict(now time.Time) bool {
+	if f := l.locked.Front(); f != nil && !now.Before(f.Value.(*limiterEntry).lockedUntil) {
+		l.remove(f.Value.(*limiterEntry))
+		return true
+	}
+	if b := l.failing.Back(); b != nil {
+		l.remove(b.Value.(*limiterEntry))
+		return true
+	}
+	return false
+}
+
+func (l *MemoryLimiter) remove(e *limiterEntry) {
+	if e.locked {
+		l.locked.Remove(e.elem)
+	} else {
+		l.failing.Remove(e.elem)
+	}
+	delete(l.entries, e.key)
+}
+
+func (l *MemoryLimiter) maxEntries() int {
+	if l.MaxEntries > 0 {
+		return l.MaxEntries
+	}
+	return DefaultLimiterEntries
+}
+
+func (l *MemoryLimiter) clock() time.Time {
+	if l.now != nil {
+		return l.now()
+	}
+	return time.Now()
+}
+
+func limiterKeys(id string, req *http.Request, clientAddr func(req *http.Request) string) []string {
+	keys := []string{"id:" + id}
+	if clientAddr == nil {
+		clientAddr = remoteAddr
+	}
+	if addr := clientAddr(req); addr != "" {
+		keys = append(keys, "addr:"+addr)
+	}
+	return keys
+}
+
+func remoteAddr(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,179 @@
+package hawk
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestMemoryLimiter(t *testing.T) {
+	now := time.Unix(1365711458, 0)
+	l := NewMemoryLimiter(3, time.Minute, 10*time.Minute)
+	l.now = func() time.Time { return now }
+
+	l.Failure("id:a")
+	l.Failure("id:a")
+	if ok, _ := l.Allow("id:a"); !ok {
+		t.Error("expected allowed, but locked out")
+	}
+
+	// the score decays by one per minute
+	now = now.Add(time.Minute)
+	l.Failure("id:a")
+	if ok, _ := l.Allow("id:a"); !ok {
+		t.Error("expected allowed, but locked out")
+	}
+
+	l.Failure("id:a")
+	ok, retryAfter := l.Allow("id:a")
+	if ok {
+		t.Error("expected locked out, but allowed")
+	}
+	if retryAfter != 10*time.Minute {
+		t.Errorf("unexpected retry after: %v", retryAfter)
+	}
+
+	// other keys are not affected
+	if ok, _ := l.Allow("id:b"); !ok {
+		t.Error("expected allowed, but locked out")
+	}
+
+	now = now.Add(10 * time.Minute)
+	if ok, _ := l.Allow("id:a"); !ok {
+		t.Error("expected allowed after lockout, but locked out")
+	}
+
+	// expired entries are swept
+	l.Failure("id:c")
+	if _, ok := l.entries["id:a"]; ok {
+		t.Error("expected entry to be swept")
+	}
+}
+
+func TestMemoryLimiter_MaxEntries(t *testing.T) {
+	now := time.Unix(1365711458, 0)
+	l := NewMemoryLimiter(2, 0, 10*time.Minute)
+	l.MaxEntries = 2
+	l.now = func() time.Time { return now }
+
+	l.Failure("id:a")
+	l.Failure("id:a")
+	now = now.Add(time.Second)
+	l.Failure("id:b")
+	now = now.Add(time.Second)
+	l.Failure("id:c")
+
+	// the oldest key which is not locked out is forgotten
+	if len(l.entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(l.entries))
+	}
+	if _, ok := l.entries["id:b"]; ok {
+		t.Error("expected id:b to be evicted")
+	}
+	if ok, _ := l.Allow("id:a"); ok {
+		t.Error("expected id:a to stay locked out")
+	}
+
+	// without Decay, the failures are kept until the key is locked out
+	now = now.Add(time.Hour)
+	l.Failure("id:c")
+	if ok, _ := l.Allow("id:c"); ok {
+		t.Error("expected id:c to be locked out")
+	}
+
+	// the failures of new keys are not recorded while every key is locked out
+	l.Failure("id:a")
+	l.Failure("id:a")
+	l.Failure("id:d")
+	if _, ok := l.entries["id:d"]; ok {
+		t.Error("expected id:d not to be recorded")
+	}
+}
+
+func TestLockedOutError_RetryAfterHeader(t *testing.T) {
+	for _, tc := range []struct {
+		retryAfter time.Duration
+		expect     string
+	}{
+		{0, "1"},
+		{1500 * time.Millisecond, "2"},
+		{time.Minute, "60"},
+	} {
+		e := &LockedOutError{RetryAfter: tc.retryAfter}
+		if act := e.RetryAfterHeader(); act != tc.expect {
+			t.Errorf("invalid Retry-After: actual=%s, expect=%s", act, tc.expect)
+		}
+	}
+}
+
+func TestServer_Authenticate_Limiter(t *testing.T) {
+	credentialStore := &testCredentialStore{
+		ID:  "dh37fgj492je",
+		Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn",
+		Alg: SHA256,
+	}
+
+	s := NewServer(credentialStore)
+	s.Limiter = NewMemoryLimiter(2, time.Minute, time.Minute)
+
+	bad := NewClient(
+		&Credential{ID: credentialStore.ID, Key: "guessed-key", Alg: SHA256},
+		&Option{TimeStamp: time.Now().Unix(), Nonce: "3hOHpR"},
+	)
+	for i := 0; i < 2; i++ {
+		h, _ := bad.Header("GET", "http://example.com:8080/resource/1")
+		r, _ := http.NewRequest("GET", "http://example.com:8080/resource/1", nil)
+		r.RemoteAddr = "192.0.2.1:1234"
+		r.Header.Set("Authorization", h)
+		_, err := s.Authenticate(r)
+		if err == nil || err.Error() != "Bad MAC" {
+			t.Fatalf("expected Bad MAC, but got %v", err)
+		}
+	}
+
+	// a valid request for the same id is throttled as well
+	good := NewClient(
+		&Credential{ID: credentialStore.ID, Key: credentialStore.Key, Alg: SHA256},
+		&Option{TimeStamp: time.Now().Unix(), Nonce: "3hOHpR"},
+	)
+	h, _ := good.Header("GET", "http://example.com:8080/resource/1")
+	r, _ := http.NewRequest("GET", "http://example.com:8080/resource/1", nil)
+	r.RemoteAddr = "198.51.100.1:1234"
+	r.Header.Set("Authorization", h)
+	_, err := s.Authenticate(r)
+	if _, ok := err.(*LockedOutError); !ok {
+		t.Fatalf("expected LockedOutError, but got %v", err)
+	}
+
+	// a failure of the store is not counted
+	failing := NewServer(credentialStoreFunc(func(id string) (*Credential, error) {
+		return nil, errors.New("unavailable")
+	}))
+	failing.Limiter = NewMemoryLimiter(1, time.Minute, time.Minute)
+	for i := 0; i < 2; i++ {
+		r, _ := http.NewRequest("GET", "http://example.com:8080/resource/1", nil)
+		r.Header.Set("Authorization", h)
+		if _, err := failing.Authenticate(r); err == nil || err.Error() != "Failed to get credential." {
+			t.Fatalf("expected Failed to get credential., but got %v", err)
+		}
+	}
+
+	// the remote address is locked out for any id
+	b := NewBewitConfig(&Credential{ID: "other", Key: "some-key", Alg: SHA256}, time.Minute)
+	bewit := b.GetBewit("http://example.com:8080/resource/1", nil)
+	r1, _ := http.NewRequest("GET", "http://example.com:8080/resource/1?bewit="+bewit, nil)
+	r1.RemoteAddr = "192.0.2.1:5678"
+	_, err = s.AuthenticateBewit(r1)
+	if _, ok := err.(*LockedOutError); !ok {
+		t.Fatalf("expected LockedOutError, but got %v", err)
+	}
+
+	// behind a proxy, the client address is taken from LimiterAddr
+	s.LimiterAddr = func(req *http.Request) string { return req.Header.Get("X-Real-Ip") }
+	r1.Header.Set("X-Real-Ip", "198.51.100.2")
+	_, err = s.AuthenticateBewit(r1)
+	if _, ok := err.(*LockedOutError); ok {
+		t.Fatal("expected another client of the proxy not to be locked out")
+	}
+}
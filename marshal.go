@@ -0,0 +1,139 @@
+package hawk
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// redacted replaces the key in String, GoString and LogValue.
+const redacted = "REDACTED"
+
+// MarshalText returns the protocol name of the algorithm, "sha256" or "sha512",
+// or an empty text for the zero value.
+func (i Alg) MarshalText() ([]byte, error) {
+	switch i {
+	case 0:
+		return []byte{}, nil
+	case SHA256:
+		return []byte("sha256"), nil
+	case SHA512:
+		return []byte("sha512"), nil
+	default:
+		return nil, errors.New("Invalid alg: " + i.String())
+	}
+}
+
+// UnmarshalText parses the name of the algorithm, e.g. "sha256", "SHA256" or "sha-256".
+// An empty text is the zero value.
+func (i *Alg) UnmarshalText(text []byte) error {
+	switch strings.Replace(strings.ToLower(string(text)), "-", "", 1) {
+	case "":
+		*i = 0
+	case "sha256":
+		*i = SHA256
+	case "sha512":
+		*i = SHA512
+	default:
+		return errors.New("Invalid alg: " + string(text))
+	}
+	return nil
+}
+
+type credentialJSON struct {
+	ID        string            `json:"id"`
+	Key       string            `json:"key"`
+	Alg       Alg               `json:"alg,omitempty"`
+	NotBefore *time.Time        `json:"not_before,omitempty"`
+	NotAfter  *time.Time        `json:"not_after,omitempty"`
+	Disabled  bool              `json:"disabled,omitempty"`
+	Scopes    []string          `json:"scopes,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+}
+
+// MarshalJSON encodes the credential, with its key, e.g.
+// {"id":"123456","key":"werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn","alg":"sha256"}.
+// The validity window is encoded as RFC 3339 times, and a zero Alg is omitted. The Signer is not encoded.
+func (c Credential) MarshalJSON() ([]byte, error) {
+	v := credentialJSON{
+		ID:       c.ID,
+		Key:      c.Key,
+		Alg:      c.Alg,
+		Disabled: c.Disabled,
+		Scopes:   c.Scopes,
+		Metadata: c.Metadata,
+	}
+	if !c.NotBefore.IsZero() {
+		v.NotBefore = &c.NotBefore
+	}
+	if !c.NotAfter.IsZero() {
+		v.NotAfter = &c.NotAfter
+	}
+	return json.Marshal(&v)
+}
+
+// UnmarshalJSON decodes a credential encoded by MarshalJSON.
+func (c *Credential) UnmarshalJSON(data []byte) error {
+	var v credentialJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*c = Credential{
+		ID:       v.ID,
+		Key:      v.Key,
+		Alg:      v.Alg,
+		Disabled: v.Disabled,
+		Scopes:   v.Scopes,
+		Metadata: v.Metadata,
+	}
+	if v.NotBefore != nil {
+		c.NotBefore = *v.NotBefore
+	}
+	if v.NotAfter != nil {
+		c.NotAfter = *v.NotAfter
+	}
+	return nil
+}
+
+// String returns the credential with the key redacted.
+func (c Credential) String() string {
+	var b strings.Builder
+	b.WriteString("{ID:")
+	b.WriteString(c.ID)
+	b.WriteString(" Key:")
+	b.WriteString(c.redactedKey())
+	b.WriteString(" Alg:")
+	b.WriteString(c.Alg.String())
+	if c.Disabled {
+		b.WriteString(" Disabled:true")
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// GoString returns the credential as Go syntax with the key redacted, for the %#v verb.
+func (c Credential) GoString() string {
+	var b strings.Builder
+	b.WriteString("hawk.Credential{ID:")
+	b.WriteString(strconv.Quote(c.ID))
+	b.WriteString(", Key:")
+	b.WriteString(strconv.Quote(c.redactedKey()))
+	b.WriteString(", Alg:")
+	if c.Alg == SHA256 || c.Alg == SHA512 {
+		b.WriteString("hawk.")
+		b.WriteString(c.Alg.String())
+	} else {
+		b.WriteString(strconv.Itoa(int(c.Alg)))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func (c Credential) redactedKey() string {
+	if c.Key == "" {
+		return ""
+	}
+	return redacted
+}
@@ -0,0 +1,19 @@
+//go:build go1.21
+// +build go1.21
+
+package hawk
+
+import "log/slog"
+
+// LogValue returns the credential as a group for log/slog, with the key redacted.
+func (c Credential) LogValue() slog.Value {
+	attrs := []slog.Attr{
+		slog.String("id", c.ID),
+		slog.String("key", c.redactedKey()),
+		slog.String("alg", c.Alg.String()),
+	}
+	if c.Disabled {
+		attrs = append(attrs, slog.Bool("disabled", true))
+	}
+	return slog.GroupValue(attrs...)
+}
@@ -0,0 +1,22 @@
+//go:build go1.21
+// +build go1.21
+
+package hawk
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestCredential_LogValue(t *testing.T) {
+	c := &Credential{ID: "123456", Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn", Alg: SHA256}
+
+	var buf bytes.Buffer
+	slog.New(slog.NewTextHandler(&buf, nil)).Info("authenticated", "credential", c)
+	if s := buf.String(); strings.Contains(s, c.Key) ||
+		!strings.Contains(s, "credential.id=123456 credential.key=REDACTED credential.alg=SHA256") {
+		t.Errorf("unexpected log: %s", s)
+	}
+}
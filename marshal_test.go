@@ -0,0 +1,98 @@
+package hawk
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAlg_Text(t *testing.T) {
+	for _, tc := range []struct {
+		text   string
+		expect Alg
+	}{
+		{text: "sha256", expect: SHA256},
+		{text: "SHA256", expect: SHA256},
+		{text: "sha-256", expect: SHA256},
+		{text: "sha512", expect: SHA512},
+	} {
+		var a Alg
+		if err := a.UnmarshalText([]byte(tc.text)); err != nil || a != tc.expect {
+			t.Errorf("unexpected alg for %s: %v, %v", tc.text, a, err)
+		}
+	}
+
+	var a Alg
+	if err := a.UnmarshalText([]byte("md5")); err == nil {
+		t.Error("expected an error for md5, but got nil")
+	}
+
+	if b, _ := SHA512.MarshalText(); string(b) != "sha512" {
+		t.Errorf("unexpected text: %s", b)
+	}
+	if _, err := Alg(10).MarshalText(); err == nil {
+		t.Error("expected an error for invalid alg, but got nil")
+	}
+	if b, err := Alg(0).MarshalText(); err != nil || len(b) != 0 {
+		t.Errorf("unexpected text for the zero value: %q, %v", b, err)
+	}
+}
+
+func TestCredential_JSON(t *testing.T) {
+	c := &Credential{
+		ID:       "123456",
+		Key:      "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn",
+		Alg:      SHA256,
+		NotAfter: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+		Scopes:   []string{"read"},
+		Metadata: map[string]string{"tenant": "example"},
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `{"id":"123456","key":"werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn","alg":"sha256","not_after":"2030-01-01T00:00:00Z","scopes":["read"],"metadata":{"tenant":"example"}}`
+	if string(b) != expect {
+		t.Errorf("unexpected json: %s", b)
+	}
+
+	var act Credential
+	if err := json.Unmarshal(b, &act); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&act, c) {
+		t.Errorf("unexpected credential: %#v", act)
+	}
+
+	if err := json.Unmarshal([]byte(`{"id":"a","key":"b","alg":"md5"}`), &act); err == nil {
+		t.Error("expected an error for invalid alg, but got nil")
+	}
+
+	// the zero value
+	b, err = json.Marshal(Credential{})
+	if err != nil || string(b) != `{"id":"","key":""}` {
+		t.Errorf("unexpected json: %s, %v", b, err)
+	}
+	if err := json.Unmarshal(b, &act); err != nil || !reflect.DeepEqual(act, Credential{}) {
+		t.Errorf("unexpected credential: %#v, %v", act, err)
+	}
+}
+
+func TestCredential_Redaction(t *testing.T) {
+	c := &Credential{ID: "123456", Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn", Alg: SHA256}
+
+	for _, format := range []string{"%v", "%+v", "%#v", "%s"} {
+		for _, v := range []interface{}{c, *c} {
+			s := fmt.Sprintf(format, v)
+			if strings.Contains(s, c.Key) || !strings.Contains(s, "123456") {
+				t.Errorf("unexpected output for %s: %s", format, s)
+			}
+		}
+	}
+	if s := fmt.Sprintf("%#v", c); s != `hawk.Credential{ID:"123456", Key:"REDACTED", Alg:hawk.SHA256}` {
+		t.Errorf("unexpected output: %s", s)
+	}
+}
@@ -0,0 +1,202 @@
+package hawk
+
+import (
+	"expvar"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// FailureReason describes why an authentication attempt was rejected.
+type FailureReason string
+
+const (
+	ReasonMissingHeader      FailureReason = "missing_header"
+	ReasonMissingAttributes  FailureReason = "missing_attributes"
+	ReasonInvalidTimestamp   FailureReason = "invalid_timestamp"
+	ReasonUnknownCredential  FailureReason = "unknown_credential"
+	ReasonInvalidCredential  FailureReason = "invalid_credential"
+	ReasonCredentialDisabled FailureReason = "credential_disabled"
+	ReasonWeakKey            FailureReason = "weak_key"
+	ReasonCredentialExpired  FailureReason = "credential_expired"
+	ReasonNotYetValid        FailureReason = "credential_not_yet_valid"
+	ReasonBadMac             FailureReason = "bad_mac"
+	ReasonMissingHash        FailureReason = "missing_hash"
+	ReasonBadHash            FailureReason = "bad_hash"
+	ReasonNonceReplay        FailureReason = "nonce_replay"
+	ReasonStaleTimestamp     FailureReason = "stale_timestamp"
+	ReasonInvalidMethod      FailureReason = "invalid_method"
+	ReasonMultipleAuth       FailureReason = "multiple_authentications"
+	ReasonInvalidBewit       FailureReason = "invalid_bewit"
+	ReasonExpired            FailureReason = "expired"
+	ReasonInvalidTarget      FailureReason = "invalid_target"
+	ReasonLockedOut          FailureReason = "locked_out"
+	ReasonRevoked            FailureReason = "revoked"
+	ReasonBewitUsed          FailureReason = "bewit_used"
+	ReasonInternal           FailureReason = "internal"
+)
+
+// Metrics collects statistics about the authentication performed by Server.
+type Metrics interface {
+	// AuthSucceeded is called when a request has been authenticated.
+	AuthSucceeded(authType AuthType, alg Alg)
+	// AuthFailed is called when a request has been rejected.
+	// alg is the algorithm of the credential, or 0 if the request was rejected before its credential was verified.
+	AuthFailed(authType AuthType, alg Alg, reason FailureReason)
+	// NonceReplayed is called when the NonceValidator rejects a nonce.
+	NonceReplayed()
+	// ClockSkew is called with the difference between the client timestamp and
+	// the server clock for every request with a valid MAC.
+	// A positive value means that the client clock is ahead of the server.
+	ClockSkew(skew time.Duration)
+}
+
+// ClockSkewBuckets are the upper bounds (in seconds, inclusive) of the clock-skew
+// histogram published by ExpvarMetrics. The skew is bucketed by its absolute value.
+var ClockSkewBuckets = []int64{1, 5, 15, 30, 60, 120, 300, 600, 3600}
+
+// ExpvarMetrics is a Metrics implementation that publishes counters via expvar.
+//
+// The published map contains the following keys:
+//
+//	success_by_type    successful authentications by AuthType
+//	success_by_alg     successful authentications by Alg
+//	failure_by_type    failed authentications by AuthType
+//	failure_by_alg     failed authentications by Alg, "unknown" if the credential was not verified
+//	failure_by_reason  failed authentications by FailureReason
+//	nonce_replays      number of rejected nonces
+//	clock_skew         histogram of the absolute clock skew in seconds
+type ExpvarMetrics struct {
+	root            *expvar.Map
+	successByType   *expvar.Map
+	successByAlg    *expvar.Map
+	failureByType   *expvar.Map
+	failureByAlg    *expvar.Map
+	failureByReason *expvar.Map
+	nonceReplays    *expvar.Int
+	skew            *skewHistogram
+}
+
+// NewExpvarMetrics creates an ExpvarMetrics and publishes it under the given name.
+// An empty name creates a collector that is not published, which is useful for tests.
+// Like expvar.Publish, it panics if the name is already registered.
+func NewExpvarMetrics(name string) *ExpvarMetrics {
+	m := &ExpvarMetrics{
+		root:            new(expvar.Map).Init(),
+		successByType:   new(expvar.Map).Init(),
+		successByAlg:    new(expvar.Map).Init(),
+		failureByType:   new(expvar.Map).Init(),
+		failureByAlg:    new(expvar.Map).Init(),
+		failureByReason: new(expvar.Map).Init(),
+		nonceReplays:    new(expvar.Int),
+		skew:            newSkewHistogram(ClockSkewBuckets),
+	}
+	m.root.Set("success_by_type", m.successByType)
+	m.root.Set("success_by_alg", m.successByAlg)
+	m.root.Set("failure_by_type", m.failureByType)
+	m.root.Set("failure_by_alg", m.failureByAlg)
+	m.root.Set("failure_by_reason", m.failureByReason)
+	m.root.Set("nonce_replays", m.nonceReplays)
+	m.root.Set("clock_skew", m.skew)
+
+	if name != "" {
+		expvar.Publish(name, m.root)
+	}
+	return m
+}
+
+// Map returns the expvar.Map holding all the counters.
+func (m *ExpvarMetrics) Map() *expvar.Map {
+	return m.root
+}
+
+func (m *ExpvarMetrics) AuthSucceeded(authType AuthType, alg Alg) {
+	m.successByType.Add(authType.String(), 1)
+	m.successByAlg.Add(alg.String(), 1)
+}
+
+func (m *ExpvarMetrics) AuthFailed(authType AuthType, alg Alg, reason FailureReason) {
+	m.failureByType.Add(authType.String(), 1)
+	if alg == 0 {
+		m.failureByAlg.Add("unknown", 1)
+	} else {
+		m.failureByAlg.Add(alg.String(), 1)
+	}
+	m.failureByReason.Add(string(reason), 1)
+}
+
+func (m *ExpvarMetrics) NonceReplayed() {
+	m.nonceReplays.Add(1)
+}
+
+func (m *ExpvarMetrics) ClockSkew(skew time.Duration) {
+	m.skew.observe(skew)
+}
+
+// skewHistogram is an expvar.Var holding a (non-cumulative) histogram of clock skews.
+type skewHistogram struct {
+	mu      sync.Mutex
+	bounds  []int64
+	counts  []int64 // len(bounds)+1, the last one is the overflow bucket
+	count   int64
+	sum     int64 // sum of the absolute skew in seconds
+	maxSkew int64
+	ahead   int64 // client clock ahead of the server
+	behind  int64 // client clock behind the server
+}
+
+func newSkewHistogram(bounds []int64) *skewHistogram {
+	b := make([]int64, len(bounds))
+	copy(b, bounds)
+	return &skewHistogram{
+		bounds: b,
+		counts: make([]int64, len(b)+1),
+	}
+}
+
+func (h *skewHistogram) observe(skew time.Duration) {
+	sec := int64(skew / time.Second)
+	abs := sec
+	if abs < 0 {
+		abs = -abs
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	i := 0
+	for ; i < len(h.bounds); i++ {
+		if abs <= h.bounds[i] {
+			break
+		}
+	}
+	h.counts[i]++
+	h.count++
+	h.sum += abs
+	if abs > h.maxSkew {
+		h.maxSkew = abs
+	}
+	if sec > 0 {
+		h.ahead++
+	} else if sec < 0 {
+		h.behind++
+	}
+}
+
+// String implements expvar.Var.
+func (h *skewHistogram) String() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := `{"buckets": {`
+	for i, b := range h.bounds {
+		s += `"le_` + strconv.FormatInt(b, 10) + `": ` + strconv.FormatInt(h.counts[i], 10) + ", "
+	}
+	s += `"inf": ` + strconv.FormatInt(h.counts[len(h.bounds)], 10) + "}, "
+	s += `"count": ` + strconv.FormatInt(h.count, 10) + ", "
+	s += `"sum": ` + strconv.FormatInt(h.sum, 10) + ", "
+	s += `"max": ` + strconv.FormatInt(h.maxSkew, 10) + ", "
+	s += `"ahead": ` + strconv.FormatInt(h.ahead, 10) + ", "
+	s += `"behind": ` + strconv.FormatInt(h.behind, 10) + "}"
+	return s
+}
@@ -0,0 +1,119 @@
+package hawk
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestExpvarMetrics_Server(t *testing.T) {
+	credentialStore := &testCredentialStore{
+		ID:  "dh37fgj492je",
+		Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn",
+		Alg: SHA256,
+	}
+	now := (&stubbedClock{}).Now(0)
+
+	m := NewExpvarMetrics("")
+	s := NewServer(credentialStore)
+	s.Metrics = m
+	s.AuthOption = &AuthOption{
+		CustomClock: &stubbedClock{},
+	}
+
+	// success: client is 20 seconds ahead
+	c := NewClient(
+		&Credential{ID: credentialStore.ID, Key: credentialStore.Key, Alg: credentialStore.Alg},
+		&Option{TimeStamp: now + 20, Nonce: "3hOHpR"},
+	)
+	h, _ := c.Header("GET", "http://example.com:8080/resource/1?b=1&a=2")
+	r, _ := http.NewRequest("GET", "http://example.com:8080/resource/1?b=1&a=2", nil)
+	r.Header.Set("Authorization", h)
+	if _, err := s.Authenticate(r); err != nil {
+		t.Fatalf("return error, %s", err)
+	}
+
+	// stale timestamp: client is 2 hours behind
+	c1 := NewClient(
+		&Credential{ID: credentialStore.ID, Key: credentialStore.Key, Alg: credentialStore.Alg},
+		&Option{TimeStamp: now - 7200, Nonce: "3hOHpR"},
+	)
+	h1, _ := c1.Header("GET", "http://example.com:8080/resource/1?b=1&a=2")
+	r1, _ := http.NewRequest("GET", "http://example.com:8080/resource/1?b=1&a=2", nil)
+	r1.Header.Set("Authorization", h1)
+	if _, err := s.Authenticate(r1); err == nil {
+		t.Error("expected return error, but got nil")
+	}
+
+	// missing header
+	r2, _ := http.NewRequest("GET", "http://example.com:8080/resource/1", nil)
+	if _, err := s.Authenticate(r2); err == nil {
+		t.Error("expected return error, but got nil")
+	}
+
+	// nonce replay
+	s.NonceValidator = &errorNonceValidator{}
+	if _, err := s.Authenticate(r); err == nil {
+		t.Error("expected return error, but got nil")
+	}
+
+	var act struct {
+		SuccessByType   map[string]int64 `json:"success_by_type"`
+		SuccessByAlg    map[string]int64 `json:"success_by_alg"`
+		FailureByType   map[string]int64 `json:"failure_by_type"`
+		FailureByAlg    map[string]int64 `json:"failure_by_alg"`
+		FailureByReason map[string]int64 `json:"failure_by_reason"`
+		NonceReplays    int64            `json:"nonce_replays"`
+		ClockSkew       struct {
+			Buckets map[string]int64 `json:"buckets"`
+			Count   int64            `json:"count"`
+			Max     int64            `json:"max"`
+			Ahead   int64            `json:"ahead"`
+			Behind  int64            `json:"behind"`
+		} `json:"clock_skew"`
+	}
+	if err := json.Unmarshal([]byte(m.Map().String()), &act); err != nil {
+		t.Fatalf("invalid json: %s", err)
+	}
+
+	if act.SuccessByType["Header"] != 1 || act.SuccessByAlg["SHA256"] != 1 {
+		t.Errorf("unexpected success counters: %v, %v", act.SuccessByType, act.SuccessByAlg)
+	}
+	if act.FailureByType["Header"] != 3 {
+		t.Errorf("unexpected failure counter: %v", act.FailureByType)
+	}
+	// the missing header is rejected before the credential is known
+	if act.FailureByAlg["SHA256"] != 2 || act.FailureByAlg["unknown"] != 1 {
+		t.Errorf("unexpected failure counters by alg: %v", act.FailureByAlg)
+	}
+	for _, reason := range []FailureReason{ReasonStaleTimestamp, ReasonMissingHeader, ReasonNonceReplay} {
+		if act.FailureByReason[string(reason)] != 1 {
+			t.Errorf("unexpected failure counter for %s: %v", reason, act.FailureByReason)
+		}
+	}
+	if act.NonceReplays != 1 {
+		t.Errorf("unexpected nonce_replays: %d", act.NonceReplays)
+	}
+	if act.ClockSkew.Count != 3 || act.ClockSkew.Ahead != 2 || act.ClockSkew.Behind != 1 || act.ClockSkew.Max != 7200 {
+		t.Errorf("unexpected clock_skew: %+v", act.ClockSkew)
+	}
+	if act.ClockSkew.Buckets["le_30"] != 2 || act.ClockSkew.Buckets["inf"] != 1 {
+		t.Errorf("unexpected clock_skew buckets: %v", act.ClockSkew.Buckets)
+	}
+}
+
+func TestExpvarMetrics_Publish(t *testing.T) {
+	m := NewExpvarMetrics("hawk_test_metrics")
+	m.AuthFailed(Bewit, SHA512, ReasonExpired)
+	m.ClockSkew(-3 * time.Second)
+
+	v := expvar.Get("hawk_test_metrics")
+	if v == nil {
+		t.Fatal("metrics are not published")
+	}
+	if v.String() != m.Map().String() {
+		t.Errorf("unexpected published value: %s", v.String())
+	}
+}
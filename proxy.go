@@ -0,0 +1,167 @@
+package hawk
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+)
+
+// IdentityHeaders are the names of the headers in which ReverseProxy forwards the verified identity to the upstream.
+// A header with an empty name is not forwarded. Headers with these names sent by the client are always removed.
+type IdentityHeaders struct {
+	ID  string
+	Ext string
+	App string
+	Dlg string
+	// Scopes is a comma-separated list of the scopes of the credential.
+	Scopes string
+}
+
+// DefaultIdentityHeaders are the IdentityHeaders of NewReverseProxy.
+var DefaultIdentityHeaders = IdentityHeaders{
+	ID:     "X-Hawk-Id",
+	Ext:    "X-Hawk-Ext",
+	App:    "X-Hawk-App",
+	Dlg:    "X-Hawk-Dlg",
+	Scopes: "X-Hawk-Scopes",
+}
+
+// DefaultMaxBodySize is the MaxBodySize of the Authorizer of NewReverseProxy.
+const DefaultMaxBodySize = 10 << 20
+
+// ReverseProxy authenticates requests with an Authorizer and forwards them to an upstream without authentication,
+// e.g. a legacy service. The Authorization header and the bewit parameter are removed from the forwarded request,
+// and the verified identity is forwarded in IdentityHeaders.
+//
+// The upstream trusts the body it gets, so the Authorizer should have a MaxBodySize, as set by NewReverseProxy:
+// otherwise the body of a request is forwarded without being verified against its payload hash.
+type ReverseProxy struct {
+	Authorizer *Authorizer
+	// Proxy forwards the authorized requests. Its ModifyResponse is set by NewReverseProxy to sign the responses.
+	Proxy           *httputil.ReverseProxy
+	IdentityHeaders IdentityHeaders
+	// SignResponses signs the responses to requests authenticated with the Authorization header,
+	// with the payload hash of the body. A body up to MaxBufferSize bytes is buffered to compute the hash,
+	// and sent after the Server-Authorization header. A larger body is streamed,
+	// and the Server-Authorization header is sent as a trailer.
+	SignResponses bool
+	// MaxBufferSize is the size up to which a response body is buffered. If 0, DefaultMaxBufferSize is used.
+	MaxBufferSize int64
+}
+
+type proxyRequestKey struct{}
+
+// NewReverseProxy initializes a new ReverseProxy to the target, for the requests allowed by the rules.
+// If no rule is given, any request authenticated with the Authorization header is allowed.
+// The request bodies are verified up to DefaultMaxBodySize bytes, larger ones are rejected.
+func NewReverseProxy(s *Server, target *url.URL, rules ...Rule) *ReverseProxy {
+	if len(rules) == 0 {
+		rules = []Rule{{}}
+	}
+	p := &ReverseProxy{
+		Authorizer:      NewAuthorizer(s, rules...),
+		Proxy:           httputil.NewSingleHostReverseProxy(target),
+		IdentityHeaders: DefaultIdentityHeaders,
+	}
+	p.Authorizer.MaxBodySize = DefaultMaxBodySize
+	p.Proxy.ModifyResponse = p.signResponse
+	return p
+}
+
+func (p *ReverseProxy) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	info, err := p.Authorizer.authenticateRequest(req)
+	if err != nil {
+		p.Authorizer.error(w, req, err)
+		return
+	}
+
+	// the original request is kept in the context, as the response is signed for it.
+	ctx := context.WithValue(NewContext(req.Context(), info), proxyRequestKey{}, req)
+	out := req.Clone(ctx)
+	out.Header.Del("Authorization")
+	if info.Type == Bewit {
+		u := removeBewitParam(out.URL)
+		out.URL = &u
+	}
+
+	h := p.IdentityHeaders
+	for _, name := range []string{h.ID, h.Ext, h.App, h.Dlg, h.Scopes} {
+		if name != "" {
+			out.Header.Del(name)
+		}
+	}
+	setHeader(out.Header, h.ID, info.Credential.ID)
+	setHeader(out.Header, h.Ext, info.Artifacts.Ext)
+	setHeader(out.Header, h.App, info.Artifacts.App)
+	setHeader(out.Header, h.Dlg, info.Artifacts.Dlg)
+	setHeader(out.Header, h.Scopes, strings.Join(info.Credential.Scopes, ","))
+
+	p.Proxy.ServeHTTP(w, out)
+}
+
+func (p *ReverseProxy) signResponse(res *http.Response) error {
+	if !p.SignResponses {
+		return nil
+	}
+	info, ok := FromContext(res.Request.Context())
+	if !ok || info.Type != Header {
+		return nil
+	}
+	// the request is missing if Proxy is served directly, e.g. behind Authorizer.Handler: the response is not signed.
+	req, ok := res.Request.Context().Value(proxyRequestKey{}).(*http.Request)
+	if !ok {
+		return nil
+	}
+
+	limit := maxBufferSize(p.MaxBufferSize)
+	body, err := ioutil.ReadAll(io.LimitReader(res.Body, limit+1))
+	if err != nil {
+		res.Body.Close()
+		return err
+	}
+	if int64(len(body)) > limit {
+		// the body is too large to be buffered: the rest of it is streamed, and signed in a trailer.
+		setContentType(res.Header, body)
+		rest := res.Body
+		res.Body = &trailerWriter{
+			body: struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(body), rest), rest},
+			hasher: newPayloadHasher(info.Credential.Alg, res.Header.Get("Content-Type")),
+			set: func(hash []byte) error {
+				h, err := p.Authorizer.Server.Header(req, info.Credential, &Option{Hash: base64.StdEncoding.EncodeToString(hash)})
+				if err != nil {
+					return err
+				}
+				res.Trailer.Set("Server-Authorization", h)
+				return nil
+			},
+		}
+		res.ContentLength = -1
+		res.Header.Del("Content-Length")
+		if res.Trailer == nil {
+			res.Trailer = http.Header{}
+		}
+		res.Trailer["Server-Authorization"] = nil
+		return nil
+	}
+
+	res.Body.Close()
+	res.Body = ioutil.NopCloser(bytes.NewReader(body))
+	res.ContentLength = int64(len(body))
+	res.TransferEncoding = nil
+	return p.Authorizer.Server.signBuffered(req, info, res.Header, body)
+}
+
+func setHeader(h http.Header, name, value string) {
+	if name != "" && value != "" {
+		h.Set(name, value)
+	}
+}
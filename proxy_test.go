@@ -0,0 +1,187 @@
+package hawk
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReverseProxy(t *testing.T) {
+	cred := &Credential{ID: "reader", Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn", Alg: SHA256, Scopes: []string{"a", "b"}}
+
+	var upstreamReq *http.Request
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamReq = r
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprint(w, "some reply")
+	}))
+	defer upstream.Close()
+	target, _ := url.Parse(upstream.URL)
+
+	p := NewReverseProxy(NewServer(&staticCredentialStore{cred}), target,
+		Rule{Methods: []string{"GET"}, AllowBewit: true},
+		Rule{Methods: []string{"POST"}},
+	)
+	p.SignResponses = true
+	ts := httptest.NewServer(p)
+	defer ts.Close()
+
+	// header
+	opt := &Option{TimeStamp: time.Now().Unix(), Nonce: "j4h3g2", Ext: "some-app-ext-data"}
+	u := ts.URL + "/resource/1?b=1&a=2"
+	h, _ := NewClient(cred, opt).Header("POST", u)
+	req, _ := http.NewRequest("POST", u, nil)
+	req.Header.Set("Authorization", h)
+	req.Header.Set("X-Hawk-Id", "admin")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusOK || string(body) != "some reply" {
+		t.Fatalf("unexpected response %d %s", res.StatusCode, body)
+	}
+	if upstreamReq.Header.Get("Authorization") != "" {
+		t.Error("expected the Authorization header to be removed")
+	}
+	for name, expect := range map[string]string{
+		"X-Hawk-Id":     "reader",
+		"X-Hawk-Ext":    "some-app-ext-data",
+		"X-Hawk-Scopes": "a,b",
+		"X-Hawk-App":    "",
+	} {
+		if act := upstreamReq.Header.Get(name); act != expect {
+			t.Errorf("%s: actual=%q, expect=%q", name, act, expect)
+		}
+	}
+	if upstreamReq.URL.RequestURI() != "/resource/1?b=1&a=2" {
+		t.Errorf("unexpected upstream request %s", upstreamReq.URL.RequestURI())
+	}
+
+	respOpt := *opt
+	respOpt.ContentType = "text/plain"
+	respOpt.Payload = string(body)
+	if ok, err := NewClient(cred, &respOpt).Authenticate(res); !ok {
+		t.Errorf("expected a signed response, got %v", err)
+	}
+
+	// bewit
+	u = ts.URL + "/resource/1?a=1"
+	b := NewBewitConfig(cred, time.Minute).GetBewit(u, nil)
+	res, err = http.Get(u + "&bewit=" + b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || upstreamReq.URL.RequestURI() != "/resource/1?a=1" {
+		t.Errorf("unexpected result %d %s", res.StatusCode, upstreamReq.URL.RequestURI())
+	}
+	if upstreamReq.Header.Get("X-Hawk-Id") != "reader" {
+		t.Error("expected the identity of the bewit")
+	}
+	if res.Header.Get("Server-Authorization") != "" {
+		t.Error("expected an unsigned response to a bewit")
+	}
+
+	// unauthenticated
+	upstreamReq = nil
+	res, err = http.Get(ts.URL + "/resource/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusUnauthorized || upstreamReq != nil {
+		t.Errorf("expected 401 without reaching the upstream, got %d", res.StatusCode)
+	}
+}
+
+func TestReverseProxy_Body(t *testing.T) {
+	cred := &Credential{ID: "writer", Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn", Alg: SHA256}
+
+	var upstreamBody []byte
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamBody, _ = ioutil.ReadAll(r.Body)
+		fmt.Fprint(w, "a reply larger than the buffer")
+	}))
+	defer upstream.Close()
+	target, _ := url.Parse(upstream.URL)
+
+	p := NewReverseProxy(NewServer(&staticCredentialStore{cred}), target)
+	p.SignResponses = true
+	p.MaxBufferSize = 8
+	ts := httptest.NewServer(p)
+	defer ts.Close()
+
+	post := func(signed, sent string) (*http.Response, *Option) {
+		opt := &Option{TimeStamp: time.Now().Unix(), Nonce: "k3j4h2", ContentType: "text/plain", Payload: signed}
+		h, _ := NewClient(cred, opt).Header("POST", ts.URL+"/resource")
+		req, _ := http.NewRequest("POST", ts.URL+"/resource", strings.NewReader(sent))
+		req.Header.Set("Content-Type", "text/plain")
+		req.Header.Set("Authorization", h)
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res, opt
+	}
+
+	// tampered body
+	res, _ := post("some body", "tampered body")
+	res.Body.Close()
+	if res.StatusCode != http.StatusUnauthorized || upstreamBody != nil {
+		t.Errorf("expected 401 without reaching the upstream, got %d", res.StatusCode)
+	}
+
+	// response larger than MaxBufferSize
+	res, opt := post("some body", "some body")
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || string(upstreamBody) != "some body" {
+		t.Fatalf("unexpected result %d %q", res.StatusCode, upstreamBody)
+	}
+	if res.Header.Get("Server-Authorization") != "" || res.Trailer.Get("Server-Authorization") == "" {
+		t.Fatal("expected Server-Authorization in a trailer")
+	}
+	respOpt := *opt
+	respOpt.ContentType = "text/plain; charset=utf-8"
+	respOpt.Payload = string(body)
+	if ok, err := NewClient(cred, &respOpt).Authenticate(res); !ok {
+		t.Errorf("expected a signed response, got %v", err)
+	}
+}
+
+func TestReverseProxy_ProxyBehindAuthorizer(t *testing.T) {
+	cred := &Credential{ID: "reader", Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn", Alg: SHA256}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "some reply")
+	}))
+	defer upstream.Close()
+	target, _ := url.Parse(upstream.URL)
+
+	s := NewServer(&staticCredentialStore{cred})
+	p := NewReverseProxy(s, target)
+	p.SignResponses = true
+	ts := httptest.NewServer(p.Authorizer.Handler(p.Proxy))
+	defer ts.Close()
+
+	opt := &Option{TimeStamp: time.Now().Unix(), Nonce: "k3j4h2"}
+	h, _ := NewClient(cred, opt).Header("GET", ts.URL+"/resource")
+	req, _ := http.NewRequest("GET", ts.URL+"/resource", nil)
+	req.Header.Set("Authorization", h)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || string(body) != "some reply" || res.Header.Get("Server-Authorization") != "" {
+		t.Errorf("expected an unsigned response, got %d %s %v", res.StatusCode, body, res.Header)
+	}
+}
@@ -0,0 +1,187 @@
+package hawk
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Errors returned by a BewitRegistry.
+var (
+	ErrBewitRevoked = errors.New("Bewit revoked.")
+	ErrBewitUsed    = errors.New("Bewit already used.")
+)
+
+// BewitToken holds the attributes of a bewit.
+type BewitToken struct {
+	// ID is the credential id.
+	ID string
+	// Exp is the expiration time in unix seconds.
+	Exp int64
+	// MAC is the base64 encoded MAC, which identifies the bewit. The registries compare its canonical encoding.
+	MAC string
+	Ext string
+}
+
+var (
+	errBewitDecode     = errors.New("Failed to decode bewit parameter.")
+	errBewitStructure  = errors.New("Invalid bewit structure.")
+	errBewitAttributes = errors.New("Missing bewit attributes.")
+	errBewitTimestamp  = errors.New("Invalid ts value.")
+	errBewitMAC        = errors.New("Invalid bewit mac.")
+)
+
+// ParseBewit decodes the value of a bewit parameter, e.g. to revoke a leaked link by its MAC.
+// The MAC is not verified, but it must be canonical base64, as it identifies the bewit.
+func ParseBewit(bewit string) (*BewitToken, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(bewit)
+	if err != nil {
+		return nil, errBewitDecode
+	}
+	parts := strings.Split(string(raw), "\\")
+	if len(parts) != 4 {
+		return nil, errBewitStructure
+	}
+	if parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return nil, errBewitAttributes
+	}
+	exp, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, errBewitTimestamp
+	}
+	mac, ok := canonicalMAC(parts[2])
+	if !ok {
+		return nil, errBewitMAC
+	}
+	return &BewitToken{ID: parts[0], Exp: exp, MAC: mac, Ext: parts[3]}, nil
+}
+
+// BewitRegistry decides whether a bewit may still be used.
+// Implementations backed by a shared store (e.g. Redis) allow a cluster of servers to share revocations
+// and used bewits.
+type BewitRegistry interface {
+	// Use is called by Server after the MAC of the bewit is verified. now is the server time in unix seconds.
+	// It returns ErrBewitRevoked or ErrBewitUsed to reject the bewit. Any other error rejects it as well.
+	// A registry with single-use bewits records the bewit as used, atomically with the check.
+	Use(b *BewitToken, now int64) error
+}
+
+// MemoryBewitRegistry is an in-memory BewitRegistry.
+//
+// A bewit does not hold the time it was issued, so it is derived from the expiration time as exp - TTL.
+// TTL should be the lifetime used by BewitConfig to issue the bewits.
+type MemoryBewitRegistry struct {
+	// SingleUse makes every bewit usable once.
+	SingleUse bool
+	TTL       time.Duration
+
+	mu            sync.Mutex
+	revokedIDs    map[string]bool
+	revokedBefore map[string]int64
+	// revokedMACs and usedMACs map the MAC to the expiration time, or 0 if unknown.
+	revokedMACs map[string]int64
+	usedMACs    map[string]int64
+	lastSweep   int64
+}
+
+// NewMemoryBewitRegistry initializes a new MemoryBewitRegistry.
+func NewMemoryBewitRegistry(singleUse bool, ttl time.Duration) *MemoryBewitRegistry {
+	return &MemoryBewitRegistry{
+		SingleUse: singleUse,
+		TTL:       ttl,
+	}
+}
+
+// RevokeID revokes all bewits of the credential.
+func (r *MemoryBewitRegistry) RevokeID(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.revokedIDs == nil {
+		r.revokedIDs = make(map[string]bool)
+	}
+	r.revokedIDs[id] = true
+}
+
+// RevokeIssuedBefore revokes the bewits of the credential issued before t.
+func (r *MemoryBewitRegistry) RevokeIssuedBefore(id string, t time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.revokedBefore == nil {
+		r.revokedBefore = make(map[string]int64)
+	}
+	if t.Unix() > r.revokedBefore[id] {
+		r.revokedBefore[id] = t.Unix()
+	}
+}
+
+// RevokeMAC revokes the bewit with the MAC.
+func (r *MemoryBewitRegistry) RevokeMAC(mac string) {
+	mac, _ = canonicalMAC(mac)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.revokedMACs == nil {
+		r.revokedMACs = make(map[string]int64)
+	}
+	if _, ok := r.revokedMACs[mac]; !ok {
+		r.revokedMACs[mac] = 0
+	}
+}
+
+func (r *MemoryBewitRegistry) Use(b *BewitToken, now int64) error {
+	mac, _ := canonicalMAC(b.MAC)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.sweep(now)
+
+	if r.revokedIDs[b.ID] {
+		return ErrBewitRevoked
+	}
+	if before, ok := r.revokedBefore[b.ID]; ok {
+		issued := b.Exp - int64(r.TTL/time.Second)
+		if issued < before {
+			return ErrBewitRevoked
+		}
+	}
+	if _, ok := r.revokedMACs[mac]; ok {
+		// the expiration time is known now, so that the entry can be swept.
+		r.revokedMACs[mac] = b.Exp
+		return ErrBewitRevoked
+	}
+
+	if !r.SingleUse {
+		return nil
+	}
+	if _, ok := r.usedMACs[mac]; ok {
+		return ErrBewitUsed
+	}
+	if r.usedMACs == nil {
+		r.usedMACs = make(map[string]int64)
+	}
+	r.usedMACs[mac] = b.Exp
+	return nil
+}
+
+// sweep removes the MACs of the expired bewits, which are rejected anyway.
+func (r *MemoryBewitRegistry) sweep(now int64) {
+	if now-r.lastSweep < 60 {
+		return
+	}
+	r.lastSweep = now
+
+	for _, m := range []map[string]int64{r.revokedMACs, r.usedMACs} {
+		for mac, exp := range m {
+			if exp != 0 && exp <= now {
+				delete(m, mac)
+			}
+		}
+	}
+}
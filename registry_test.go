@@ -0,0 +1,154 @@
+package hawk
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseBewit(t *testing.T) {
+	b, err := ParseBewit("MTIzNDU2XDQ1MTE0ODQ2MjFcMzFjMmNkbUJFd1NJRVZDOVkva1NFb2c3d3YrdEVNWjZ3RXNmOGNHU2FXQT1cc29tZS1hcHAtZGF0YQ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := BewitToken{
+		ID:  "123456",
+		Exp: 4511484621,
+		MAC: "31c2cdmBEwSIEVC9Y/kSEog7wv+tEMZ6wEsf8cGSaWA=",
+		Ext: "some-app-data",
+	}
+	if *b != expect {
+		t.Errorf("unexpected bewit: actual=%+v, expect=%+v", *b, expect)
+	}
+
+	for _, bewit := range []string{"%%%", "MTIzNDU2XDQ1MTE0ODQ2MjE", "XDQ1MTE0ODQ2MjFcYWJjXA", "MTIzXGFiY1xhYmNc"} {
+		if _, err := ParseBewit(bewit); err == nil {
+			t.Errorf("expected an error for %s, but got nil", bewit)
+		}
+	}
+}
+
+func TestServer_AuthenticateBewit_Registry(t *testing.T) {
+	credentialStore := &testCredentialStore{
+		ID:  "123456",
+		Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn",
+		Alg: SHA256,
+	}
+	cred := &Credential{ID: credentialStore.ID, Key: credentialStore.Key, Alg: credentialStore.Alg}
+	issued := time.Unix((&stubbedClock{}).Now(0), 0)
+
+	url := "http://example.com/resource/4?a=1"
+	bewit := NewBewitConfig(cred, time.Minute).GetBewit(url, &stubbedClock{})
+	b, _ := ParseBewit(bewit)
+
+	authenticate := func(registry BewitRegistry) error {
+		s := NewServer(credentialStore)
+		s.AuthOption = &AuthOption{CustomClock: &stubbedClock{}}
+		s.BewitRegistry = registry
+		r, _ := http.NewRequest("GET", url+"&bewit="+bewit, nil)
+		_, err := s.AuthenticateBewit(r)
+		return err
+	}
+
+	for _, tc := range []struct {
+		name   string
+		revoke func(r *MemoryBewitRegistry)
+		expect string
+	}{
+		{name: "not revoked", revoke: func(r *MemoryBewitRegistry) {}},
+		{name: "other id", revoke: func(r *MemoryBewitRegistry) { r.RevokeID("654321") }},
+		{name: "id", revoke: func(r *MemoryBewitRegistry) { r.RevokeID("123456") }, expect: "Bewit revoked."},
+		{name: "mac", revoke: func(r *MemoryBewitRegistry) { r.RevokeMAC(b.MAC) }, expect: "Bewit revoked."},
+		{
+			name:   "issued before",
+			revoke: func(r *MemoryBewitRegistry) { r.RevokeIssuedBefore("123456", issued.Add(time.Second)) },
+			expect: "Bewit revoked.",
+		},
+		{
+			name:   "issued after",
+			revoke: func(r *MemoryBewitRegistry) { r.RevokeIssuedBefore("123456", issued) },
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			registry := NewMemoryBewitRegistry(false, time.Minute)
+			tc.revoke(registry)
+
+			err := authenticate(registry)
+			if tc.expect == "" && err != nil {
+				t.Errorf("return error, %s", err)
+			}
+			if tc.expect != "" && (err == nil || err.Error() != tc.expect) {
+				t.Errorf("unexpected error: actual=%v, expect=%s", err, tc.expect)
+			}
+		})
+	}
+
+	// single use
+	registry := NewMemoryBewitRegistry(true, time.Minute)
+	if err := authenticate(registry); err != nil {
+		t.Errorf("return error, %s", err)
+	}
+	if err := authenticate(registry); err == nil || err.Error() != "Bewit already used." {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// the MAC encoded with other padding bits does not get around the revocation nor the single use
+	const alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+	i := len(b.MAC) - 2
+	mac := b.MAC[:i] + string(alphabet[strings.IndexByte(alphabet, b.MAC[i])^1]) + b.MAC[i+1:]
+	altered := base64.RawURLEncoding.EncodeToString([]byte(b.ID + "\\" + strconv.FormatInt(b.Exp, 10) + "\\" + mac + "\\" + b.Ext))
+	if _, err := ParseBewit(altered); err == nil {
+		t.Error("expected an error for a non-canonical mac, but got nil")
+	}
+	for _, registry := range []*MemoryBewitRegistry{registry, NewMemoryBewitRegistry(false, time.Minute)} {
+		registry.RevokeMAC(b.MAC)
+		s := NewServer(credentialStore)
+		s.AuthOption = &AuthOption{CustomClock: &stubbedClock{}}
+		s.BewitRegistry = registry
+		r, _ := http.NewRequest("GET", url+"&bewit="+altered, nil)
+		if _, err := s.AuthenticateBewit(r); err == nil {
+			t.Error("expected an error for an altered bewit, but got nil")
+		}
+	}
+	registry.RevokeMAC(mac)
+	if len(registry.revokedMACs) != 1 {
+		t.Errorf("expected the altered mac to be revoked as the same bewit: %v", registry.revokedMACs)
+	}
+
+	// the registry is not consulted for an invalid bewit
+	registry1 := NewMemoryBewitRegistry(true, time.Minute)
+	s := NewServer(credentialStore)
+	s.AuthOption = &AuthOption{CustomClock: &stubbedClock{}}
+	s.BewitRegistry = registry1
+	r, _ := http.NewRequest("GET", "http://example.com/resource/5?a=1&bewit="+bewit, nil)
+	if _, err := s.AuthenticateBewit(r); err == nil {
+		t.Error("expected an error for bad mac, but got nil")
+	}
+	if len(registry1.usedMACs) != 0 {
+		t.Errorf("bewit with bad mac is consumed: %v", registry1.usedMACs)
+	}
+}
+
+func TestMemoryBewitRegistry_Sweep(t *testing.T) {
+	r := NewMemoryBewitRegistry(true, time.Minute)
+	r.RevokeMAC("revoked")
+
+	if err := r.Use(&BewitToken{ID: "a", Exp: 1060, MAC: "used"}, 1000); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Use(&BewitToken{ID: "a", Exp: 1060, MAC: "revoked"}, 1000); err != ErrBewitRevoked {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the expired bewits are forgotten
+	r.Use(&BewitToken{ID: "a", Exp: 2000, MAC: "other"}, 1100)
+	if _, ok := r.usedMACs["used"]; ok {
+		t.Error("expired used bewit is not swept")
+	}
+	if _, ok := r.revokedMACs["revoked"]; ok {
+		t.Error("expired revoked bewit is not swept")
+	}
+}
@@ -0,0 +1,269 @@
+package hawk
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// DefaultMaxBufferSize is the size up to which ResponseSigner, ReverseProxy and Transport buffer a body,
+// when their MaxBufferSize is not set.
+const DefaultMaxBufferSize = 1 << 20
+
+// ResponseSigner signs the responses to requests authenticated with the Authorization header,
+// with the payload hash of the body and the Content-Type of the response.
+// The AuthInfo of the request is taken from its context, e.g. as set by Authorizer.Handler.
+//
+// The ResponseWriter passed to the handler implements http.Flusher, and http.Hijacker and http.Pusher
+// if the underlying one does. The response on a hijacked connection, and pushed responses, are not signed.
+type ResponseSigner struct {
+	Server *Server
+	// Stream sends the body as it is written, and the Server-Authorization header as a trailer,
+	// which the client gets once it has read the body.
+	// Otherwise the body is buffered, and the Server-Authorization header is sent before it,
+	// up to MaxBufferSize bytes: a larger body is streamed.
+	Stream bool
+	// MaxBufferSize is the size up to which a body is buffered. If 0, DefaultMaxBufferSize is used.
+	MaxBufferSize int64
+	// PayloadTrailer, with Stream, sends the Server-Authorization header before the body, without a payload hash,
+	// and the payload hash in the PayloadTrailer trailer, which the client verifies with Client.VerifyTrailer.
+	PayloadTrailer bool
+}
+
+// NewResponseSigner initializes a new ResponseSigner.
+func NewResponseSigner(s *Server) *ResponseSigner {
+	return &ResponseSigner{Server: s}
+}
+
+// Handler returns a middleware which signs the responses of next.
+func (rs *ResponseSigner) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		info, ok := FromContext(req.Context())
+		if !ok || info.Type != Header {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		sw := &signingResponseWriter{
+			ResponseWriter: w,
+			server:         rs.Server,
+			req:            req,
+			info:           info,
+			stream:         rs.Stream,
+			trailer:        rs.Stream && rs.PayloadTrailer,
+			maxBuffer:      rs.MaxBufferSize,
+		}
+		next.ServeHTTP(sw.wrap(), req)
+		sw.finish()
+	})
+}
+
+// signingResponseWriter buffers or hashes the body written by the handler, and signs the response.
+type signingResponseWriter struct {
+	http.ResponseWriter
+	server    *Server
+	req       *http.Request
+	info      *AuthInfo
+	stream    bool
+	trailer   bool
+	maxBuffer int64
+
+	status      int
+	wroteHeader bool
+	hijacked    bool
+	buf         bytes.Buffer
+	hasher      *payloadHasher
+}
+
+func (w *signingResponseWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	if code >= 100 && code <= 199 && code != http.StatusSwitchingProtocols {
+		// informational responses are sent as they are, before the final one which is signed.
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.wroteHeader = true
+	w.status = code
+}
+
+func (w *signingResponseWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.stream {
+		if int64(w.buf.Len()+len(p)) <= maxBufferSize(w.maxBuffer) {
+			return w.buf.Write(p)
+		}
+		// the body is too large to be buffered: what has been buffered is sent, and the rest is streamed.
+		w.stream = true
+		if w.buf.Len() > 0 {
+			w.start(w.buf.Bytes())
+			w.hasher.Write(w.buf.Bytes())
+			if _, err := w.ResponseWriter.Write(w.buf.Bytes()); err != nil {
+				return 0, err
+			}
+			w.buf = bytes.Buffer{}
+		}
+	}
+	w.start(p)
+	w.hasher.Write(p)
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush sends the header and the body written so far of a streamed response.
+func (w *signingResponseWriter) Flush() {
+	if !w.stream {
+		return
+	}
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	w.start(nil)
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// start sends the header of a streamed response, which announces the Server-Authorization trailer,
+// or the Server-Authorization header and the PayloadTrailer.
+// The Content-Type is detected from the first bytes of the body, as net/http does, since it is covered by the hash.
+func (w *signingResponseWriter) start(p []byte) {
+	if w.hasher != nil {
+		return
+	}
+	h := w.Header()
+	setContentType(h, p)
+	h.Del("Content-Length")
+	if w.trailer {
+		if sah := w.sign(""); sah != "" {
+			h.Set("Server-Authorization", sah)
+		}
+		h.Add("Trailer", PayloadTrailer)
+	} else {
+		h.Add("Trailer", "Server-Authorization")
+	}
+	w.hasher = newPayloadHasher(w.info.Credential.Alg, h.Get("Content-Type"))
+	w.ResponseWriter.WriteHeader(w.status)
+}
+
+func (w *signingResponseWriter) finish() {
+	if w.hijacked {
+		return
+	}
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	h := w.Header()
+
+	if w.trailer {
+		w.start(nil)
+		v, err := w.server.payloadTrailerValue(w.req, w.info.Credential, w.info.Artifacts, w.hasher.Sum())
+		if err == nil {
+			h.Set(PayloadTrailer, v)
+		}
+		return
+	}
+	if w.stream {
+		w.start(nil)
+		if sah := w.sign(w.hasher.String()); sah != "" {
+			h.Set("Server-Authorization", sah)
+		}
+		return
+	}
+
+	body := w.buf.Bytes()
+	if err := w.server.signBuffered(w.req, w.info, h, body); err != nil {
+		// FIXME: logging error
+		h.Del("Server-Authorization")
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.Write(body)
+}
+
+// wrap returns w, which implements http.Hijacker and http.Pusher if the underlying ResponseWriter does.
+func (w *signingResponseWriter) wrap() http.ResponseWriter {
+	_, hj := w.ResponseWriter.(http.Hijacker)
+	_, pu := w.ResponseWriter.(http.Pusher)
+	switch {
+	case hj && pu:
+		return struct {
+			*signingResponseWriter
+			http.Hijacker
+			http.Pusher
+		}{w, signingHijacker{w}, w.ResponseWriter.(http.Pusher)}
+	case hj:
+		return struct {
+			*signingResponseWriter
+			http.Hijacker
+		}{w, signingHijacker{w}}
+	case pu:
+		return struct {
+			*signingResponseWriter
+			http.Pusher
+		}{w, w.ResponseWriter.(http.Pusher)}
+	}
+	return w
+}
+
+// signingHijacker hijacks the connection of the underlying ResponseWriter. The response is not signed.
+type signingHijacker struct {
+	w *signingResponseWriter
+}
+
+func (h signingHijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := h.w.ResponseWriter.(http.Hijacker).Hijack()
+	if err == nil {
+		h.w.hijacked = true
+	}
+	return conn, rw, err
+}
+
+// sign returns the Server-Authorization header for the payload hash.
+// If it fails, the response is sent unsigned, and is rejected by the client.
+func (w *signingResponseWriter) sign(hash string) string {
+	h, err := w.server.Header(w.req, w.info.Credential, &Option{Hash: hash})
+	if err != nil {
+		// FIXME: logging error
+		return ""
+	}
+	return h
+}
+
+// signBuffered sets the Content-Type, Content-Length and Server-Authorization headers of a response
+// whose body is buffered, with the payload hash of the body.
+func (s *Server) signBuffered(req *http.Request, info *AuthInfo, h http.Header, body []byte) error {
+	setContentType(h, body)
+	if len(body) > 0 {
+		h.Set("Content-Length", strconv.Itoa(len(body)))
+	}
+	ph := &PayloadHash{
+		ContentType: h.Get("Content-Type"),
+		Payload:     string(body),
+		Alg:         info.Credential.Alg,
+	}
+	sah, err := s.Header(req, info.Credential, &Option{Hash: ph.String()})
+	if err != nil {
+		return err
+	}
+	h.Set("Server-Authorization", sah)
+	return nil
+}
+
+// setContentType sets the Content-Type of a response, if it is not set, from the first bytes of its body,
+// as net/http does: it is covered by the payload hash, so it must be known before the body is sent.
+func setContentType(h http.Header, p []byte) {
+	if _, ok := h["Content-Type"]; !ok && len(p) > 0 {
+		h.Set("Content-Type", http.DetectContentType(p))
+	}
+}
+
+func maxBufferSize(n int64) int64 {
+	if n <= 0 {
+		return DefaultMaxBufferSize
+	}
+	return n
+}
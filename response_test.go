@@ -0,0 +1,184 @@
+package hawk
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"net/textproto"
+	"testing"
+	"time"
+)
+
+func TestResponseSigner(t *testing.T) {
+	cred := &Credential{ID: "123456", Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn", Alg: SHA256}
+	s := NewServer(&staticCredentialStore{cred})
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/json" {
+			w.Header().Set("Content-Type", "application/json")
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, "some ")
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+		fmt.Fprint(w, "reply")
+	})
+
+	for _, stream := range []bool{false, true} {
+		rs := NewResponseSigner(s)
+		rs.Stream = stream
+		ts := httptest.NewServer(NewAuthorizer(s, Rule{AllowBewit: true}).Handler(rs.Handler(handler)))
+
+		for _, path := range []string{"/json", "/text"} {
+			opt := &Option{TimeStamp: time.Now().Unix(), Nonce: "j4h3g2"}
+			u := ts.URL + path
+			h, _ := NewClient(cred, opt).Header("GET", u)
+			req, _ := http.NewRequest("GET", u, nil)
+			req.Header.Set("Authorization", h)
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := ioutil.ReadAll(res.Body)
+			res.Body.Close()
+
+			if res.StatusCode != http.StatusCreated || string(body) != "some reply" {
+				t.Errorf("stream=%v %s: unexpected response %d %s", stream, path, res.StatusCode, body)
+			}
+			if stream != (res.Header.Get("Server-Authorization") == "") {
+				t.Errorf("stream=%v %s: unexpected Server-Authorization header %q", stream, path, res.Header.Get("Server-Authorization"))
+			}
+			if path == "/text" && res.Header.Get("Content-Type") != "text/plain; charset=utf-8" {
+				t.Errorf("stream=%v: unexpected Content-Type %s", stream, res.Header.Get("Content-Type"))
+			}
+
+			respOpt := *opt
+			respOpt.ContentType = res.Header.Get("Content-Type")
+			respOpt.Payload = string(body)
+			if ok, err := NewClient(cred, &respOpt).Authenticate(res); !ok {
+				t.Errorf("stream=%v %s: expected a signed response, got %v", stream, path, err)
+			}
+			respOpt.Payload = "tampered"
+			if ok, _ := NewClient(cred, &respOpt).Authenticate(res); ok {
+				t.Errorf("stream=%v %s: expected the hash to cover the body", stream, path)
+			}
+		}
+
+		// a response to a bewit is not signed.
+		u := ts.URL + "/json"
+		res, err := http.Get(u + "?bewit=" + NewBewitConfig(cred, time.Minute).GetBewit(u, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if res.StatusCode != http.StatusCreated || res.Header.Get("Server-Authorization") != "" || res.Trailer.Get("Server-Authorization") != "" {
+			t.Errorf("stream=%v: unexpected response to a bewit %d %v %v", stream, res.StatusCode, res.Header, res.Trailer)
+		}
+
+		ts.Close()
+	}
+}
+
+func TestResponseSigner_MaxBufferSize(t *testing.T) {
+	cred := &Credential{ID: "123456", Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn", Alg: SHA256}
+	s := NewServer(&staticCredentialStore{cred})
+
+	rs := NewResponseSigner(s)
+	rs.MaxBufferSize = 8
+	ts := httptest.NewServer(NewAuthorizer(s, Rule{}).Handler(rs.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/hijack" {
+			conn, buf, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer conn.Close()
+			buf.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 8\r\nConnection: close\r\n\r\nhijacked")
+			buf.Flush()
+			return
+		}
+		fmt.Fprint(w, "some ")
+		fmt.Fprint(w, "reply")
+	}))))
+	defer ts.Close()
+
+	for _, path := range []string{"/large", "/hijack"} {
+		opt := &Option{TimeStamp: time.Now().Unix(), Nonce: "j4h3g2"}
+		u := ts.URL + path
+		h, _ := NewClient(cred, opt).Header("GET", u)
+		req, _ := http.NewRequest("GET", u, nil)
+		req.Header.Set("Authorization", h)
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+
+		if path == "/hijack" {
+			if string(body) != "hijacked" || res.Header.Get("Server-Authorization") != "" {
+				t.Errorf("unexpected hijacked response %s %v", body, res.Header)
+			}
+			continue
+		}
+		if string(body) != "some reply" || res.Header.Get("Server-Authorization") != "" {
+			t.Fatalf("expected a streamed response, got %s %v", body, res.Header)
+		}
+		if ok, err := NewClient(cred, opt).VerifyResponse(res, body); !ok {
+			t.Errorf("expected a signed response, got %v", err)
+		}
+	}
+}
+
+func TestResponseSigner_Informational(t *testing.T) {
+	cred := &Credential{ID: "123456", Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn", Alg: SHA256}
+	s := NewServer(&staticCredentialStore{cred})
+
+	ts := httptest.NewServer(NewAuthorizer(s, Rule{}).Handler(NewResponseSigner(s).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// an HTTP/1.1 ResponseWriter can be hijacked, but does not push
+		if _, ok := w.(http.Hijacker); !ok {
+			t.Error("expected an http.Hijacker")
+		}
+		if _, ok := w.(http.Pusher); ok {
+			t.Error("unexpected http.Pusher")
+		}
+		w.Header().Set("Link", "</style.css>; rel=preload")
+		w.WriteHeader(http.StatusEarlyHints)
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, "some reply")
+	}))))
+	defer ts.Close()
+
+	var informational []int
+	trace := &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			informational = append(informational, code)
+			return nil
+		},
+	}
+	opt := &Option{TimeStamp: time.Now().Unix(), Nonce: "j4h3g2"}
+	h, _ := NewClient(cred, opt).Header("GET", ts.URL)
+	req, _ := http.NewRequest("GET", ts.URL, nil)
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+	req.Header.Set("Authorization", h)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+
+	if len(informational) != 1 || informational[0] != http.StatusEarlyHints {
+		t.Errorf("expected 103 Early Hints, got %v", informational)
+	}
+	if res.StatusCode != http.StatusCreated || string(body) != "some reply" {
+		t.Fatalf("unexpected response %d %s", res.StatusCode, body)
+	}
+	if ok, err := NewClient(cred, opt).VerifyResponse(res, body); !ok {
+		t.Errorf("expected a signed response, got %v", err)
+	}
+}
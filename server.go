@@ -12,25 +12,45 @@ import (
 )
 
 type Server struct {
-	CredentialStore  CredentialStore
-	NonceValidator   NonceValidator
-	TimeStampSkew    time.Duration
-	LocaltimeOffset  time.Duration
-	Payload          string
-	AuthOption       *AuthOption
+	CredentialStore CredentialStore
+	NonceValidator  NonceValidator
+	TimeStampSkew   time.Duration
+	LocaltimeOffset time.Duration
+	Payload         string
+	AuthOption      *AuthOption
+	Metrics         Metrics
+	Limiter         Limiter
+	TargetResolver  TargetResolver
+	BewitRegistry   BewitRegistry
+	// KeyPolicy, if set, rejects credentials with a weak key as invalid.
+	KeyPolicy *KeyPolicy
+	// LimiterAddr returns the address of the client of a request, which the Limiter throttles besides the id,
+	// or "" to throttle by id only. If nil, the host of the RemoteAddr of the request is used:
+	// behind a reverse proxy, it is the address of the proxy, which every client would share.
+	LimiterAddr func(req *http.Request) string
 }
 
 type AuthOption struct {
+	// Deprecated: use HeaderTargetResolver as Server.TargetResolver.
 	CustomHostNameHeader string
-	CustomHostPort       string
-	CustomClock          Clock
-	CustomURIHeader      string
+	// Deprecated: use HeaderTargetResolver as Server.TargetResolver.
+	CustomHostPort string
+	CustomClock    Clock
+	// Deprecated: use HeaderTargetResolver as Server.TargetResolver.
+	CustomURIHeader string
 }
 
+// CredentialStore gets the credential for an id.
+// For an unknown id, GetCredential returns ErrCredentialNotFound (or a nil credential).
+// Other errors, e.g. an unreachable backend, fail the authentication as internal errors,
+// which are not counted by the Limiter.
 type CredentialStore interface {
 	GetCredential(id string) (*Credential, error)
 }
 
+// ErrCredentialNotFound is returned by a CredentialStore for an unknown id.
+var ErrCredentialNotFound = errors.New("Credential not found.")
+
 type NonceValidator interface {
 	Validate(key, nonce string, ts int64) bool
 }
@@ -45,9 +65,22 @@ func NewServer(cs CredentialStore) *Server {
 // Authenticate authenticate the Hawk request from the HTTP request.
 // Successful case returns credential information about requested user.
 func (s *Server) Authenticate(req *http.Request) (*Credential, error) {
+	cred, _, err := s.AuthenticateArtifacts(req)
+	return cred, err
+}
+
+// AuthenticateArtifacts is like Authenticate, but also returns the artifacts of the authenticated request.
+func (s *Server) AuthenticateArtifacts(req *http.Request) (*Credential, *Option, error) {
+	return s.authenticate(req, s.Payload, s.Payload != "")
+}
+
+// authenticate authenticates the request with the Authorization header.
+// If hasPayload, the payload hash is required for a non-empty payload, and verified if present.
+func (s *Server) authenticate(req *http.Request, payload string, hasPayload bool) (*Credential, *Option, error) {
 	// 0 is treated as empty. set to default value.
-	if s.TimeStampSkew == 0 {
-		s.TimeStampSkew = 60 * time.Second
+	skew := s.TimeStampSkew
+	if skew == 0 {
+		skew = 60 * time.Second
 	}
 
 	clock := getClock(s.AuthOption)
@@ -55,17 +88,22 @@ func (s *Server) Authenticate(req *http.Request) (*Credential, error) {
 
 	authzHeader := req.Header.Get("Authorization")
 	if authzHeader == "" {
-		return nil, errors.New("Authorization header not found.")
+		return nil, nil, s.fail(Header, 0, ReasonMissingHeader, "Authorization header not found.")
 	}
 	authzAttributes := parseHawkHeader(authzHeader)
 	if authzAttributes["id"] == "" || authzAttributes["ts"] == "" ||
 		authzAttributes["nonce"] == "" || authzAttributes["mac"] == "" {
-		return nil, errors.New("Missing attributes.")
+		return nil, nil, s.fail(Header, 0, ReasonMissingAttributes, "Missing attributes.")
+	}
+
+	keys := s.limiterKeys(authzAttributes["id"], req)
+	if err := s.allow(Header, keys); err != nil {
+		return nil, nil, err
 	}
 
 	ts, err := strconv.ParseInt(authzAttributes["ts"], 10, 64)
 	if err != nil {
-		return nil, errors.New("Invalid ts value.")
+		return nil, nil, s.fail(Header, 0, ReasonInvalidTimestamp, "Invalid ts value.")
 	}
 
 	artifacts := &Option{
@@ -77,180 +115,212 @@ func (s *Server) Authenticate(req *http.Request) (*Credential, error) {
 		Dlg:       authzAttributes["dlg"],
 	}
 
-	cred, err := s.CredentialStore.GetCredential(authzAttributes["id"])
+	// an unknown or invalid credential is replaced with a decoy, and goes through
+	// the same MAC verification so that the response time does not reveal it.
+	cred, reason, err := s.lookupCredential(authzAttributes["id"])
 	if err != nil {
-		// FIXME: logging error
-		return nil, errors.New("Failed to get Credential.")
-	}
-	if cred.Key == "" {
-		return nil, errors.New("Invalid Credential.")
+		return nil, nil, s.fail(Header, 0, reason, err.Error())
 	}
 
-	host := req.Host
-	uri := req.URL.String()
-	if s.AuthOption != nil {
-		// set to custom host(and port) value
-		if s.AuthOption.CustomHostNameHeader != "" {
-			host = req.Header.Get(s.AuthOption.CustomHostNameHeader)
-		}
-		if s.AuthOption.CustomHostPort != "" {
-			// forces override a value.
-			host = s.AuthOption.CustomHostPort
-		}
-		if s.AuthOption.CustomURIHeader != "" {
-			uri = req.Header.Get(s.AuthOption.CustomURIHeader)
-			host = "" // make sure the host value is derived from the custom uri.
-		}
+	u, err := s.targetResolver().ResolveTarget(req)
+	if err != nil {
+		return nil, nil, s.fail(Header, 0, ReasonInvalidTarget, "Invalid request target.")
 	}
 
 	m := &Mac{
 		Type:       Header,
 		Credential: cred,
-		Uri:        uri,
 		Method:     req.Method,
-		HostPort:   host,
 		Option:     artifacts,
+		url:        u,
 	}
-	mac, err := m.String()
+	mac, err := m.digest()
 	if err != nil {
 		//FIXME: logging error
-		return nil, errors.New("Failed to calculate MAC.")
+		return nil, nil, s.fail(Header, 0, ReasonInternal, "Failed to calculate MAC.")
 	}
 
-	if !fixedTimeComparison(mac, authzAttributes["mac"]) {
+	if !macEqual(mac, authzAttributes["mac"]) || reason != "" {
+		s.failure(keys)
+		if reason == "" {
+			reason = ReasonBadMac
+		}
+		return nil, nil, s.fail(Header, failureAlg(cred, reason), reason, "Bad MAC")
+	}
 
-		return nil, errors.New("Bad MAC")
+	if err := s.checkCredential(Header, cred, now); err != nil {
+		return nil, nil, err
 	}
 
-	if s.Payload != "" {
+	if hasPayload && (payload != "" || artifacts.Hash != "") {
 		if artifacts.Hash == "" {
-			return nil, errors.New("Missing required payload hash.")
+			return nil, nil, s.fail(Header, cred.Alg, ReasonMissingHash, "Missing required payload hash.")
 		}
 
 		ph := &PayloadHash{
 			ContentType: req.Header.Get("Content-Type"),
-			Payload:     s.Payload,
+			Payload:     payload,
 			Alg:         cred.Alg,
 		}
-		if !fixedTimeComparison(ph.String(), artifacts.Hash) {
-			return nil, errors.New("Bad payload hash.")
+		if !macEqual(ph.hash(), artifacts.Hash) {
+			return nil, nil, s.fail(Header, cred.Alg, ReasonBadHash, "Bad payload hash.")
 		}
 	}
 
+	if s.Metrics != nil {
+		s.Metrics.ClockSkew(time.Duration(artifacts.TimeStamp-now) * time.Second)
+	}
+
 	if s.NonceValidator != nil {
-		if !s.NonceValidator.Validate(cred.Key, artifacts.Nonce, artifacts.TimeStamp) {
-			return nil, errors.New("Invalid nonce.")
+		if !s.NonceValidator.Validate(nonceKey(cred), artifacts.Nonce, artifacts.TimeStamp) {
+			if s.Metrics != nil {
+				s.Metrics.NonceReplayed()
+			}
+			return nil, nil, s.fail(Header, cred.Alg, ReasonNonceReplay, "Invalid nonce.")
 		}
 	}
-	if math.Abs(float64((artifacts.TimeStamp)-(now))) > s.TimeStampSkew.Seconds() {
+	if math.Abs(float64((artifacts.TimeStamp)-(now))) > skew.Seconds() {
 		//FIXME: logging timestamp
-		return nil, errors.New("Stale timestamp")
+		s.fail(Header, cred.Alg, ReasonStaleTimestamp, "Stale timestamp")
+		tsm, _ := (&TsMac{TimeStamp: now, Credential: cred}).digest()
+		return nil, nil, &StaleTimestampError{TimeStamp: now, TsMac: base64.StdEncoding.EncodeToString(tsm)}
 	}
 
-	return cred, nil
+	s.succeed(Header, cred)
+	return cred, artifacts, nil
+}
+
+// StaleTimestampError is returned by Server when the timestamp of an authenticated request is out of TimeStampSkew.
+// It holds the server time, and its MAC with the credential of the request.
+type StaleTimestampError struct {
+	TimeStamp int64
+	TsMac     string
+}
+
+func (e *StaleTimestampError) Error() string {
+	return "Stale timestamp"
+}
+
+// WWWAuthenticateHeader returns a value to be set in the WWW-Authenticate header,
+// e.g. `Hawk ts="1365711458", tsm="...", error="Stale timestamp"`.
+// The client verifies it with Client.TimestampChallenge, and retries the request with the server time.
+func (e *StaleTimestampError) WWWAuthenticateHeader() string {
+	var b strings.Builder
+	b.WriteString("Hawk ")
+	writeHeaderAttr(&b, "ts", strconv.FormatInt(e.TimeStamp, 10))
+	if e.TsMac != "" {
+		writeHeaderAttr(&b, "tsm", e.TsMac)
+	}
+	writeHeaderAttr(&b, "error", e.Error())
+	return b.String()
 }
 
 // AuthenticateBewit authenticate the Hawk bewit request from the HTTP request.
 // Successful case returns credential information about requested user.
 func (s *Server) AuthenticateBewit(req *http.Request) (*Credential, error) {
+	cred, _, err := s.AuthenticateBewitArtifacts(req)
+	return cred, err
+}
+
+// AuthenticateBewitArtifacts is like AuthenticateBewit, but also returns the artifacts of the bewit.
+// The TimeStamp of the artifacts is the expiration time of the bewit.
+func (s *Server) AuthenticateBewitArtifacts(req *http.Request) (*Credential, *Option, error) {
 	clock := getClock(s.AuthOption)
 	now := clock.Now(s.LocaltimeOffset)
 
-	encodedBewit := req.URL.Query().Get("bewit")
+	encodedBewit, n := bewitParam(req.URL.RawQuery)
 	if encodedBewit == "" {
-		return nil, errors.New("Empty bewit.")
+		return nil, nil, s.fail(Bewit, 0, ReasonMissingAttributes, "Empty bewit.")
+	}
+	if n > 1 {
+		return nil, nil, s.fail(Bewit, 0, ReasonInvalidBewit, "Multiple bewit parameters.")
 	}
 
 	if req.Method != "GET" && req.Method != "HEAD" {
-		return nil, errors.New("Invalid method.")
+		return nil, nil, s.fail(Bewit, 0, ReasonInvalidMethod, "Invalid method.")
 	}
 
 	if req.Header.Get("Authorization") != "" {
-		return nil, errors.New("Multiple authentications")
+		return nil, nil, s.fail(Bewit, 0, ReasonMultipleAuth, "Multiple authentications")
 	}
 
-	rawBewit, err := base64.RawURLEncoding.DecodeString(encodedBewit)
+	bewit, err := ParseBewit(encodedBewit)
 	if err != nil {
-		return nil, errors.New("Failed to decode bewit parameter.")
-	}
-
-	parsedBewit := strings.Split(string(rawBewit), "\\")
-	if len(parsedBewit) != 4 {
-		return nil, errors.New("Invalid bewit structure.")
-	}
-
-	bewit := map[string]string{
-		"id":  parsedBewit[0],
-		"exp": parsedBewit[1],
-		"mac": parsedBewit[2],
-		"ext": parsedBewit[3],
-	}
-
-	if bewit["id"] == "" || bewit["exp"] == "" || bewit["mac"] == "" {
-		return nil, errors.New("Missing bewit attributes.")
+		reason := ReasonInvalidBewit
+		switch err {
+		case errBewitAttributes:
+			reason = ReasonMissingAttributes
+		case errBewitTimestamp:
+			reason = ReasonInvalidTimestamp
+		}
+		return nil, nil, s.fail(Bewit, 0, reason, err.Error())
 	}
 
-	ts, err := strconv.ParseInt(bewit["exp"], 10, 64)
-	if err != nil {
-		return nil, errors.New("Invalid ts value.")
+	keys := s.limiterKeys(bewit.ID, req)
+	if err := s.allow(Bewit, keys); err != nil {
+		return nil, nil, err
 	}
 
+	ts := bewit.Exp
 	if ts <= now {
-		return nil, errors.New("Access expired.")
+		return nil, nil, s.fail(Bewit, 0, ReasonExpired, "Access expired.")
 	}
 
-	cred, err := s.CredentialStore.GetCredential(bewit["id"])
+	cred, reason, err := s.lookupCredential(bewit.ID)
 	if err != nil {
-		// FIXME: logging error
-		return nil, errors.New("Failed to get Credential.")
+		return nil, nil, s.fail(Bewit, 0, reason, err.Error())
 	}
-	if cred.Key == "" {
-		return nil, errors.New("Invalid Credential.")
-	}
-
-	removedBewitURL := removeBewitParam(req.URL)
 
-	host := req.Host
-	uri := removedBewitURL.String()
-	if s.AuthOption != nil {
-		// set to custom host(and port) value
-		if s.AuthOption.CustomHostNameHeader != "" {
-			host = req.Header.Get(s.AuthOption.CustomHostNameHeader)
-		}
-		if s.AuthOption.CustomHostPort != "" {
-			// forces override a value.
-			host = s.AuthOption.CustomHostPort
-		}
-		if s.AuthOption.CustomURIHeader != "" {
-			u, _ := url.Parse(req.Header.Get(s.AuthOption.CustomURIHeader))
-			tempUri := removeBewitParam(u)
-			uri = tempUri.String()
-		}
+	u, err := s.targetResolver().ResolveTarget(req)
+	if err != nil {
+		return nil, nil, s.fail(Bewit, 0, ReasonInvalidTarget, "Invalid request target.")
 	}
+	removedBewitURL := removeBewitParam(u)
 
 	m := &Mac{
 		Type:       Bewit,
 		Credential: cred,
-		Uri:        uri,
 		Method:     req.Method,
-		HostPort:   host,
+		url:        &removedBewitURL,
 		Option: &Option{
 			TimeStamp: ts,
 			Nonce:     "",
-			Ext:       bewit["ext"],
+			Ext:       bewit.Ext,
 		},
 	}
-	mac, err := m.String()
+	mac, err := m.digest()
 	if err != nil {
 		//FIXME: logging error
-		return nil, errors.New("Failed to calculate MAC.")
+		return nil, nil, s.fail(Bewit, 0, ReasonInternal, "Failed to calculate MAC.")
+	}
+
+	if !macEqual(mac, bewit.MAC) || reason != "" {
+		s.failure(keys)
+		if reason == "" {
+			reason = ReasonBadMac
+		}
+		return nil, nil, s.fail(Bewit, failureAlg(cred, reason), reason, "Bad mac.")
+	}
+
+	if err := s.checkCredential(Bewit, cred, now); err != nil {
+		return nil, nil, err
 	}
 
-	if !fixedTimeComparison(mac, bewit["mac"]) {
-		return nil, errors.New("Bad mac.")
+	if s.BewitRegistry != nil {
+		switch err := s.BewitRegistry.Use(bewit, now); err {
+		case nil:
+		case ErrBewitRevoked:
+			return nil, nil, s.fail(Bewit, cred.Alg, ReasonRevoked, err.Error())
+		case ErrBewitUsed:
+			return nil, nil, s.fail(Bewit, cred.Alg, ReasonBewitUsed, err.Error())
+		default:
+			//FIXME: logging error
+			return nil, nil, s.fail(Bewit, cred.Alg, ReasonInternal, "Failed to check bewit.")
+		}
 	}
 
-	return cred, nil
+	s.succeed(Bewit, cred)
+	return cred, m.Option, nil
 }
 
 // Header builds a value to be set in the Server-Authorization header.
@@ -280,29 +350,17 @@ func (s *Server) Header(req *http.Request, cred *Credential, opt *Option) (strin
 		Dlg:       authzAttributes["dlg"],
 	}
 
-	host := req.Host
-	uri := req.URL.String()
-	if s.AuthOption != nil {
-		// set to custom host(and port) value
-		if s.AuthOption.CustomHostNameHeader != "" {
-			host = req.Header.Get(s.AuthOption.CustomHostNameHeader)
-		}
-		if s.AuthOption.CustomHostPort != "" {
-			// forces override a value.
-			host = s.AuthOption.CustomHostPort
-		}
-		if s.AuthOption.CustomURIHeader != "" {
-			uri = req.Header.Get(s.AuthOption.CustomURIHeader)
-		}
+	u, err := s.targetResolver().ResolveTarget(req)
+	if err != nil {
+		return "", errors.New("Invalid request target.")
 	}
 
 	m := &Mac{
 		Type:       Response,
 		Credential: cred,
-		Uri:        uri,
 		Method:     req.Method,
-		HostPort:   host,
 		Option:     artifacts,
+		url:        u,
 	}
 
 	mac, err := m.String()
@@ -311,17 +369,114 @@ func (s *Server) Header(req *http.Request, cred *Credential, opt *Option) (strin
 		return "", errors.New("Failed to calculate MAC.")
 	}
 
-	header := "Hawk " + `mac="` + mac + `"`
+	var b strings.Builder
+	b.Grow(128 + len(opt.Ext))
+	b.WriteString("Hawk ")
+	writeHeaderAttr(&b, "mac", mac)
 
 	if opt.Hash != "" {
-		header = header + ", " + `hash="` + opt.Hash + `"`
+		writeHeaderAttr(&b, "hash", opt.Hash)
 	}
 
 	if opt.Ext != "" {
-		header = header + ", " + `ext="` + opt.Ext + `"`
+		writeHeaderAttr(&b, "ext", opt.Ext)
+	}
+
+	return b.String(), nil
+}
+
+// lookupCredential gets the credential from the CredentialStore.
+// If the credential is unknown, has no key or a weak key, it returns a decoy credential with the failure reason.
+// If the CredentialStore fails otherwise, it returns the error.
+func (s *Server) lookupCredential(id string) (*Credential, FailureReason, error) {
+	cred, err := s.CredentialStore.GetCredential(id)
+	if err != nil && !errors.Is(err, ErrCredentialNotFound) {
+		// FIXME: logging error
+		return nil, ReasonInternal, errors.New("Failed to get credential.")
+	}
+	if cred == nil {
+		return decoyCredential(id, SHA256), ReasonUnknownCredential, nil
+	}
+	if !hasKey(cred) {
+		return decoyCredential(id, cred.Alg), ReasonInvalidCredential, nil
+	}
+	if s.KeyPolicy != nil && cred.Signer == nil && s.KeyPolicy.Validate(cred) != nil {
+		// FIXME: logging error
+		return decoyCredential(id, cred.Alg), ReasonWeakKey, nil
+	}
+	return cred, "", nil
+}
+
+// checkCredential rejects a disabled credential, or a credential out of its validity window.
+func (s *Server) checkCredential(authType AuthType, cred *Credential, now int64) error {
+	err := cred.Valid(now)
+	if err != nil && s.Metrics != nil {
+		reason := ReasonCredentialExpired
+		switch err {
+		case ErrCredentialDisabled:
+			reason = ReasonCredentialDisabled
+		case ErrCredentialNotYetValid:
+			reason = ReasonNotYetValid
+		}
+		s.Metrics.AuthFailed(authType, cred.Alg, reason)
+	}
+	return err
+}
+
+// fail reports the failure to the Metrics and returns an error with the given message.
+// alg is the algorithm of the credential, or 0 if the credential is not known.
+func (s *Server) fail(authType AuthType, alg Alg, reason FailureReason, msg string) error {
+	if s.Metrics != nil {
+		s.Metrics.AuthFailed(authType, alg, reason)
+	}
+	return errors.New(msg)
+}
+
+// failureAlg returns the algorithm of the credential of a request with a bad MAC,
+// or 0 if it is a decoy, i.e. if the credential was rejected for reason.
+func failureAlg(cred *Credential, reason FailureReason) Alg {
+	if reason != "" {
+		return 0
 	}
+	return cred.Alg
+}
+
+func (s *Server) limiterKeys(id string, req *http.Request) []string {
+	if s.Limiter == nil {
+		return nil
+	}
+	return limiterKeys(id, req, s.LimiterAddr)
+}
+
+// allow consults the Limiter and returns a LockedOutError if any of the keys is locked out.
+func (s *Server) allow(authType AuthType, keys []string) error {
+	if s.Limiter == nil {
+		return nil
+	}
+	for _, k := range keys {
+		if ok, retryAfter := s.Limiter.Allow(k); !ok {
+			if s.Metrics != nil {
+				s.Metrics.AuthFailed(authType, 0, ReasonLockedOut)
+			}
+			return &LockedOutError{RetryAfter: retryAfter}
+		}
+	}
+	return nil
+}
+
+func (s *Server) failure(keys []string) {
+	if s.Limiter == nil {
+		return
+	}
+	for _, k := range keys {
+		s.Limiter.Failure(k)
+	}
+}
 
-	return header, nil
+func (s *Server) succeed(authType AuthType, cred *Credential) {
+	if s.Metrics != nil {
+		s.Metrics.AuthSucceeded(authType, cred.Alg)
+	}
 }
 
 func getClock(authOption *AuthOption) Clock {
@@ -334,16 +489,71 @@ func getClock(authOption *AuthOption) Clock {
 	return clock
 }
 
-func removeBewitParam(u *url.URL) url.URL {
-	removedQuery := &url.Values{}
-	for key, _ := range u.Query() {
-		if key == "bewit" {
+// bewitParam returns the value of the first bewit parameter in the raw query,
+// and the number of bewit parameters.
+func bewitParam(rawQuery string) (string, int) {
+	value := ""
+	n := 0
+	for rawQuery != "" {
+		var seg string
+		if i := strings.IndexByte(rawQuery, '&'); i >= 0 {
+			seg, rawQuery = rawQuery[:i], rawQuery[i+1:]
+		} else {
+			seg, rawQuery = rawQuery, ""
+		}
+		if !strings.HasPrefix(seg, "bewit=") {
 			continue
 		}
-		removedQuery.Add(key, u.Query().Get(key))
+		n++
+		if n == 1 {
+			v, err := url.QueryUnescape(seg[len("bewit="):])
+			if err != nil {
+				continue
+			}
+			value = v
+		}
 	}
+	return value, n
+}
+
+// StripBewit returns a copy of the URL without the bewit parameter, which is the URL covered by the bewit MAC,
+// and the value of the parameter. The rest of the raw query is kept byte for byte.
+func StripBewit(u *url.URL) (*url.URL, string) {
+	bewit, _ := bewitParam(u.RawQuery)
+	stripped := removeBewitParam(u)
+	return &stripped, bewit
+}
+
+// removeBewitParam returns a copy of the URL without the bewit parameter.
+// The rest of the raw query is kept byte for byte, as the client signed it.
+func removeBewitParam(u *url.URL) url.URL {
 	removedUrl := *u
-	removedUrl.RawQuery = removedQuery.Encode()
+	q := u.RawQuery
+
+	start := 0
+	for start <= len(q) {
+		end := strings.IndexByte(q[start:], '&')
+		if end < 0 {
+			end = len(q)
+		} else {
+			end += start
+		}
+		if strings.HasPrefix(q[start:end], "bewit=") {
+			switch {
+			case end < len(q):
+				// remove the parameter and the following separator.
+				q = q[:start] + q[end+1:]
+			case start > 0:
+				// the last parameter, remove the preceding separator.
+				q = q[:start-1]
+			default:
+				q = ""
+			}
+			break
+		}
+		start = end + 1
+	}
+	removedUrl.RawQuery = q
 
 	return removedUrl
 }
@@ -1,9 +1,12 @@
 package hawk
 
 import (
-	"testing"
-
 	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
 	"time"
 )
 
@@ -55,7 +58,7 @@ func TestServer_Authenticate(t *testing.T) {
 		t.Errorf("return error, %s", err)
 	} else {
 		expect, _ := credentialStore.GetCredential(id)
-		if *act != *expect {
+		if !reflect.DeepEqual(act, expect) {
 			t.Error("Invalid return value")
 		}
 	}
@@ -90,7 +93,7 @@ func TestServer_Authenticate(t *testing.T) {
 		t.Errorf("return error, %s", err)
 	} else {
 		expect2, _ := credentialStore.GetCredential(id)
-		if *act2 != *expect2 {
+		if !reflect.DeepEqual(act2, expect2) {
 			t.Error("Invalid return value")
 		}
 	}
@@ -125,7 +128,7 @@ func TestServer_Authenticate(t *testing.T) {
 		t.Errorf("return error, %s", err)
 	} else {
 		expect3, _ := credentialStore.GetCredential(id)
-		if *act3 != *expect3 {
+		if !reflect.DeepEqual(act3, expect3) {
 			t.Error("Invalid return value")
 		}
 	}
@@ -165,7 +168,7 @@ func TestServer_Authenticate(t *testing.T) {
 		t.Errorf("return error, %s", err)
 	} else {
 		expect4, _ := credentialStore.GetCredential(id)
-		if *act4 != *expect4 {
+		if !reflect.DeepEqual(act4, expect4) {
 			t.Error("Invalid return value")
 		}
 	}
@@ -200,7 +203,7 @@ func TestServer_Authenticate(t *testing.T) {
 		t.Errorf("return error, %s", err)
 	} else {
 		expect5, _ := credentialStore.GetCredential(id)
-		if *act5 != *expect5 {
+		if !reflect.DeepEqual(act5, expect5) {
 			t.Error("Invalid return value")
 		}
 	}
@@ -440,6 +443,66 @@ func TestServer_AuthenticateBewit(t *testing.T) {
 
 }
 
+func TestServer_AuthenticateBewit_RawQuery(t *testing.T) {
+	credentialStore := &testCredentialStore{
+		ID:  "123456",
+		Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn",
+		Alg: SHA256,
+	}
+	cred := &Credential{ID: credentialStore.ID, Key: credentialStore.Key, Alg: credentialStore.Alg}
+	s := NewServer(credentialStore)
+
+	for _, tc := range []struct {
+		name   string
+		before string
+		after  string
+	}{
+		{name: "repeated", before: "a=1&a=2&", after: "&b=1&b=1"},
+		{name: "unordered", before: "z=1&", after: "&b=2&a=3"},
+		{name: "percent-encoded", before: "q=a%2Bb%20c&r=%7e+x&", after: "&s=%2f"},
+		{name: "empty values", before: "a&b=&", after: "&&c="},
+		{name: "first", before: "", after: "&b=2&a=1"},
+		{name: "last", before: "b=2&a=1&", after: ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			signed := "http://example.com:8080/resource/4?" + tc.before + strings.TrimPrefix(tc.after, "&")
+			if tc.after == "" {
+				signed = strings.TrimSuffix(signed, "&")
+			}
+			bewit := NewBewitConfig(cred, time.Minute).GetBewit(signed, nil)
+
+			r, _ := http.NewRequest("GET", "http://example.com:8080/resource/4?"+tc.before+"bewit="+bewit+tc.after, nil)
+			if _, err := s.AuthenticateBewit(r); err != nil {
+				t.Errorf("return error, %s", err)
+			}
+		})
+	}
+
+	// the query is not normalized, so a reordered query does not match.
+	bewit := NewBewitConfig(cred, time.Minute).GetBewit("http://example.com:8080/resource/4?b=2&a=1", nil)
+	r, _ := http.NewRequest("GET", "http://example.com:8080/resource/4?a=1&b=2&bewit="+bewit, nil)
+	if _, err := s.AuthenticateBewit(r); err == nil {
+		t.Error("expected an error for reordered query, but got nil")
+	}
+
+	// multiple bewit parameters are rejected.
+	r1, _ := http.NewRequest("GET", "http://example.com:8080/resource/4?bewit="+bewit+"&b=2&a=1&bewit="+bewit, nil)
+	if _, err := s.AuthenticateBewit(r1); err == nil || err.Error() != "Multiple bewit parameters." {
+		t.Errorf("unexpected error, %v", err)
+	}
+}
+
+func TestStripBewit(t *testing.T) {
+	u, _ := url.Parse("http://example.com/resource?a=%2f&bewit=YWJj&b=1")
+	stripped, bewit := StripBewit(u)
+	if stripped.String() != "http://example.com/resource?a=%2f&b=1" || bewit != "YWJj" {
+		t.Errorf("unexpected result: %s, %s", stripped, bewit)
+	}
+	if u.RawQuery != "a=%2f&bewit=YWJj&b=1" {
+		t.Errorf("the URL is modified: %s", u)
+	}
+}
+
 func TestServer_AuthenticateBewit_Fail(t *testing.T) {
 	id := "123456"
 
@@ -721,3 +784,170 @@ func TestServer_Header(t *testing.T) {
 		t.Error("unexpected header response, actual=" + act3)
 	}
 }
+
+type staticCredentialStore struct {
+	cred *Credential
+}
+
+func (s *staticCredentialStore) GetCredential(id string) (*Credential, error) {
+	if id != s.cred.ID {
+		return nil, ErrCredentialNotFound
+	}
+	return s.cred, nil
+}
+
+func TestServer_Authenticate_CredentialState(t *testing.T) {
+	key := "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn"
+	now := time.Unix(1365711458, 0)
+	clock := &stubbedClock{}
+
+	for _, tc := range []struct {
+		name   string
+		cred   *Credential
+		expect error
+		reason FailureReason
+	}{
+		{
+			name: "valid",
+			cred: &Credential{NotBefore: now.Add(-time.Hour), NotAfter: now.Add(time.Hour)},
+		},
+		{
+			name:   "disabled",
+			cred:   &Credential{Disabled: true},
+			expect: ErrCredentialDisabled,
+			reason: ReasonCredentialDisabled,
+		},
+		{
+			name:   "expired",
+			cred:   &Credential{NotAfter: now.Add(-time.Second)},
+			expect: ErrCredentialExpired,
+			reason: ReasonCredentialExpired,
+		},
+		{
+			name:   "not yet valid",
+			cred:   &Credential{NotBefore: now.Add(time.Second)},
+			expect: ErrCredentialNotYetValid,
+			reason: ReasonNotYetValid,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cred := tc.cred
+			cred.ID, cred.Key, cred.Alg = "123456", key, SHA256
+			cred.Scopes = []string{"read"}
+			cred.Metadata = map[string]string{"tenant": "example"}
+
+			m := NewExpvarMetrics("")
+			s := NewServer(&staticCredentialStore{cred: cred})
+			s.AuthOption = &AuthOption{CustomClock: clock}
+			s.Metrics = m
+
+			c := NewClient(cred, &Option{TimeStamp: clock.Now(0), Nonce: "3hOHpR"})
+			h, _ := c.Header("GET", "http://example.com:8080/resource/1")
+			r, _ := http.NewRequest("GET", "http://example.com:8080/resource/1", nil)
+			r.Header.Set("Authorization", h)
+
+			act, err := s.Authenticate(r)
+			if err != tc.expect {
+				t.Errorf("unexpected error: actual=%v, expect=%v", err, tc.expect)
+			}
+			if err == nil && (act.Metadata["tenant"] != "example" || !act.HasScope("read")) {
+				t.Errorf("metadata is not returned: %+v", act)
+			}
+
+			bewit := NewBewitConfig(cred, time.Minute).GetBewit("http://example.com:8080/resource/1", clock)
+			r1, _ := http.NewRequest("GET", "http://example.com:8080/resource/1?bewit="+bewit, nil)
+			if _, err := s.AuthenticateBewit(r1); err != tc.expect {
+				t.Errorf("unexpected error: actual=%v, expect=%v", err, tc.expect)
+			}
+			if tc.reason != "" && m.failureByReason.Get(string(tc.reason)).String() != "2" {
+				t.Errorf("unexpected failure reasons: %s", m.failureByReason)
+			}
+		})
+	}
+
+	// the state of an unverified credential is not revealed
+	s := NewServer(&staticCredentialStore{cred: &Credential{ID: "123456", Key: key, Alg: SHA256, Disabled: true}})
+	s.AuthOption = &AuthOption{CustomClock: clock}
+	r, _ := http.NewRequest("GET", "http://example.com:8080/resource/1", nil)
+	r.Header.Set("Authorization", `Hawk id="123456", ts="1365711458", nonce="3hOHpR", mac="YQ=="`)
+	if _, err := s.Authenticate(r); err == nil || err.Error() != "Bad MAC" {
+		t.Errorf("expected Bad MAC, but got %v", err)
+	}
+}
+
+type unknownCredentialStore struct{}
+
+func (u *unknownCredentialStore) GetCredential(id string) (*Credential, error) {
+	return nil, ErrCredentialNotFound
+}
+
+func TestServer_Authenticate_UniformFailure(t *testing.T) {
+	credentialStore := &testCredentialStore{
+		ID:  "dh37fgj492je",
+		Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn",
+		Alg: SHA256,
+	}
+
+	c := NewClient(
+		&Credential{ID: credentialStore.ID, Key: credentialStore.Key, Alg: SHA256},
+		&Option{TimeStamp: time.Now().Unix(), Nonce: "3hOHpR"},
+	)
+	h, _ := c.Header("GET", "http://example.com:8080/resource/1")
+
+	// unknown id and invalid credentials are reported as a bad MAC
+	for _, cs := range []CredentialStore{
+		&unknownCredentialStore{},
+		&testCredentialStore{ID: credentialStore.ID, Alg: SHA256},
+		&testCredentialStore{ID: credentialStore.ID, Key: "other-key", Alg: SHA256},
+	} {
+		r, _ := http.NewRequest("GET", "http://example.com:8080/resource/1", nil)
+		r.Header.Set("Authorization", h)
+
+		s := NewServer(cs)
+		act, err := s.Authenticate(r)
+		if act != nil {
+			t.Error("got an server autnentication result, expected=nil")
+		}
+		if err == nil || err.Error() != "Bad MAC" {
+			t.Errorf("expected Bad MAC, but got %v", err)
+		}
+	}
+
+	// malformed mac lengths
+	for _, mac := range []string{"", "YQ==", "not-base64", strings.Repeat("A", 128)} {
+		r, _ := http.NewRequest("GET", "http://example.com:8080/resource/1", nil)
+		r.Header.Set("Authorization", `Hawk id="dh37fgj492je", ts="`+strconv.FormatInt(time.Now().Unix(), 10)+`", nonce="3hOHpR", mac="`+mac+`"`)
+
+		s := NewServer(credentialStore)
+		act, err := s.Authenticate(r)
+		if act != nil || err == nil {
+			t.Errorf("expected an error for mac=%q", mac)
+		}
+	}
+}
+
+func BenchmarkServer_Authenticate(b *testing.B) {
+	credentialStore := &testCredentialStore{
+		ID:  "dh37fgj492je",
+		Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn",
+		Alg: SHA256,
+	}
+
+	c := NewClient(
+		&Credential{ID: credentialStore.ID, Key: credentialStore.Key, Alg: SHA256},
+		&Option{TimeStamp: time.Now().Unix(), Nonce: "3hOHpR", Ext: "some-app-data"},
+	)
+	h, _ := c.Header("GET", "http://example.com:8080/resource/1?b=1&a=2")
+	r, _ := http.NewRequest("GET", "http://example.com:8080/resource/1?b=1&a=2", nil)
+	r.Header.Set("Authorization", h)
+
+	s := NewServer(credentialStore)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := s.Authenticate(r); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package hawk
+
+import "errors"
+
+// Signer computes MACs, so that the key of a credential does not have to be held in memory,
+// e.g. a signer backed by a KMS or an HSM.
+type Signer interface {
+	// Sign returns the MAC of the normalized string with the key of the credential and its Alg.
+	// The credential identifies the key, e.g. by its ID or by its Metadata.
+	Sign(cred *Credential, normalized []byte) ([]byte, error)
+}
+
+// HMACSigner computes MACs locally with the Key of the credential. It is used when Credential.Signer is nil.
+type HMACSigner struct{}
+
+func (HMACSigner) Sign(cred *Credential, normalized []byte) ([]byte, error) {
+	if cred.Key == "" {
+		return nil, errors.New("Empty key.")
+	}
+	return hmacSum(cred.Alg, cred.Key, normalized), nil
+}
+
+// sign returns the MAC of the normalized string with the Signer of the credential, or with HMACSigner.
+func sign(cred *Credential, normalized []byte) ([]byte, error) {
+	if cred.Signer != nil {
+		return cred.Signer.Sign(cred, normalized)
+	}
+	return HMACSigner{}.Sign(cred, normalized)
+}
+
+// hasKey reports whether the credential can compute MACs.
+func hasKey(cred *Credential) bool {
+	return cred.Key != "" || cred.Signer != nil
+}
+
+// nonceKey returns the key given to the NonceValidator, the id if the key is not held in memory.
+func nonceKey(cred *Credential) string {
+	if cred.Key == "" {
+		return cred.ID
+	}
+	return cred.Key
+}
@@ -0,0 +1,31 @@
+package hawk
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestHMACSigner_Sign(t *testing.T) {
+	cred := &Credential{ID: "123456", Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn", Alg: SHA256}
+	m := &Mac{Type: Header, Credential: cred, Uri: "http://example.com/resource", Method: "GET", Option: &Option{TimeStamp: 1365711458, Nonce: "3hOHpR"}}
+	expect, _ := m.digest()
+
+	ns, _ := m.Normalized()
+	act, err := HMACSigner{}.Sign(cred, []byte(ns))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(act, expect) {
+		t.Errorf("unexpected mac: %x", act)
+	}
+
+	if _, err := (HMACSigner{}).Sign(&Credential{ID: "123456", Alg: SHA256}, []byte(ns)); err == nil {
+		t.Error("expected an error for empty key, but got nil")
+	}
+
+	// a credential without Signer is signed with HMACSigner
+	m.Credential = &Credential{ID: "123456", Alg: SHA256}
+	if _, err := m.digest(); err == nil {
+		t.Error("expected an error for empty key without Signer, but got nil")
+	}
+}
@@ -0,0 +1,222 @@
+package hawk
+
+import (
+	"container/list"
+	"errors"
+	"sync"
+	"time"
+)
+
+// DefaultCacheSize is the number of entries of a CachedCredentialStore when Size is not set.
+const DefaultCacheSize = 10000
+
+// CachedCredentialStore caches the credentials of a CredentialStore in an LRU cache.
+//
+// Unknown ids (ErrCredentialNotFound) are cached for NegativeTTL, so that a flood of unknown ids does not reach
+// the store. They share the cache with the credentials, so that they can not grow it beyond Size.
+// Other errors are not cached.
+// It is safe for concurrent use.
+type CachedCredentialStore struct {
+	Store CredentialStore
+	// Size is the maximum number of cached entries. If 0, DefaultCacheSize is used.
+	Size int
+	// TTL is the lifetime of a cached credential.
+	TTL time.Duration
+	// NegativeTTL is the lifetime of a cached unknown id. If 0, unknown ids are not cached.
+	NegativeTTL time.Duration
+
+	mu    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+	now   func() time.Time
+}
+
+type cacheEntry struct {
+	id      string
+	cred    *Credential
+	err     error
+	expires time.Time
+}
+
+// NewCachedCredentialStore initializes a new CachedCredentialStore.
+func NewCachedCredentialStore(store CredentialStore, size int, ttl, negativeTTL time.Duration) *CachedCredentialStore {
+	return &CachedCredentialStore{
+		Store:       store,
+		Size:        size,
+		TTL:         ttl,
+		NegativeTTL: negativeTTL,
+	}
+}
+
+func (s *CachedCredentialStore) GetCredential(id string) (*Credential, error) {
+	if e, ok := s.get(id); ok {
+		return e.cred.Clone(), e.err
+	}
+
+	cred, err := s.Store.GetCredential(id)
+	if err == nil && cred == nil {
+		err = ErrCredentialNotFound
+	}
+	s.add(id, cred, err)
+	if err != nil {
+		return nil, err
+	}
+	return cred.Clone(), nil
+}
+
+// get returns the entry for the id, unless it has expired. Entries are not modified once added.
+func (s *CachedCredentialStore) get(id string) (*cacheEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	el, ok := s.items[id]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*cacheEntry)
+	if !s.clock().Before(e.expires) {
+		s.remove(el)
+		return nil, false
+	}
+	s.ll.MoveToFront(el)
+	return e, true
+}
+
+func (s *CachedCredentialStore) add(id string, cred *Credential, err error) {
+	ttl := s.TTL
+	if err != nil {
+		if !errors.Is(err, ErrCredentialNotFound) {
+			return
+		}
+		ttl = s.NegativeTTL
+	}
+	if ttl <= 0 {
+		return
+	}
+	cred = cred.Clone()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.items == nil {
+		s.ll = list.New()
+		s.items = make(map[string]*list.Element)
+	}
+	e := &cacheEntry{id: id, cred: cred, err: err, expires: s.clock().Add(ttl)}
+	if el, ok := s.items[id]; ok {
+		el.Value = e
+		s.ll.MoveToFront(el)
+		return
+	}
+	s.items[id] = s.ll.PushFront(e)
+	size := s.Size
+	if size <= 0 {
+		size = DefaultCacheSize
+	}
+	if s.ll.Len() > size {
+		s.remove(s.ll.Back())
+	}
+}
+
+func (s *CachedCredentialStore) remove(el *list.Element) {
+	s.ll.Remove(el)
+	delete(s.items, el.Value.(*cacheEntry).id)
+}
+
+// Invalidate removes the cached entry for the id, e.g. after its key has been rotated.
+func (s *CachedCredentialStore) Invalidate(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.items[id]; ok {
+		s.remove(el)
+	}
+}
+
+// Purge removes all the cached entries.
+func (s *CachedCredentialStore) Purge() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ll = nil
+	s.items = nil
+}
+
+// Len returns the number of the cached entries.
+func (s *CachedCredentialStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.items)
+}
+
+func (s *CachedCredentialStore) clock() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
+
+// CoalescingCredentialStore shares one call to the CredentialStore between concurrent lookups of the same id.
+// It is safe for concurrent use.
+type CoalescingCredentialStore struct {
+	Store CredentialStore
+
+	mu    sync.Mutex
+	calls map[string]*storeCall
+}
+
+type storeCall struct {
+	wg   sync.WaitGroup
+	cred *Credential
+	err  error
+}
+
+// NewCoalescingCredentialStore initializes a new CoalescingCredentialStore.
+func NewCoalescingCredentialStore(store CredentialStore) *CoalescingCredentialStore {
+	return &CoalescingCredentialStore{Store: store}
+}
+
+func (s *CoalescingCredentialStore) GetCredential(id string) (*Credential, error) {
+	s.mu.Lock()
+	if s.calls == nil {
+		s.calls = make(map[string]*storeCall)
+	}
+	if c, ok := s.calls[id]; ok {
+		s.mu.Unlock()
+		c.wg.Wait()
+		return c.cred.Clone(), c.err
+	}
+	c := &storeCall{}
+	c.wg.Add(1)
+	s.calls[id] = c
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.calls, id)
+		s.mu.Unlock()
+		c.wg.Done()
+	}()
+	c.cred, c.err = s.Store.GetCredential(id)
+	return c.cred.Clone(), c.err
+}
+
+// ChainCredentialStore looks up the credential in the stores in order, and returns the first one found,
+// e.g. to try a local file before a remote store. If none is found, it returns ErrCredentialNotFound,
+// or the first other error of a store, as the credential may be in the failed store.
+type ChainCredentialStore []CredentialStore
+
+func (s ChainCredentialStore) GetCredential(id string) (*Credential, error) {
+	var storeErr error
+	for _, store := range s {
+		cred, err := store.GetCredential(id)
+		if err == nil && cred != nil {
+			return cred, nil
+		}
+		if err != nil && !errors.Is(err, ErrCredentialNotFound) && storeErr == nil {
+			storeErr = err
+		}
+	}
+	if storeErr != nil {
+		return nil, storeErr
+	}
+	return nil, ErrCredentialNotFound
+}
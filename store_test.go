@@ -0,0 +1,185 @@
+package hawk
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type countingCredentialStore struct {
+	creds map[string]*Credential
+	calls int32
+	delay time.Duration
+}
+
+func (s *countingCredentialStore) GetCredential(id string) (*Credential, error) {
+	atomic.AddInt32(&s.calls, 1)
+	time.Sleep(s.delay)
+	c, ok := s.creds[id]
+	if !ok {
+		return nil, ErrCredentialNotFound
+	}
+	return c, nil
+}
+
+func newCountingCredentialStore() *countingCredentialStore {
+	return &countingCredentialStore{creds: map[string]*Credential{
+		"a": {ID: "a", Key: "key-a", Alg: SHA256},
+		"b": {ID: "b", Key: "key-b", Alg: SHA256},
+		"c": {ID: "c", Key: "key-c", Alg: SHA256},
+	}}
+}
+
+func TestCachedCredentialStore(t *testing.T) {
+	backend := newCountingCredentialStore()
+	now := time.Unix(1365711458, 0)
+	s := NewCachedCredentialStore(backend, 2, time.Minute, time.Second)
+	s.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		c, err := s.GetCredential("a")
+		if err != nil || c.Key != "key-a" {
+			t.Fatalf("unexpected credential: %v, %v", c, err)
+		}
+		// the cached credential is not modified by the caller
+		c.Key = "modified"
+	}
+	if backend.calls != 1 {
+		t.Errorf("unexpected calls: %d", backend.calls)
+	}
+
+	// negative caching
+	for i := 0; i < 3; i++ {
+		if _, err := s.GetCredential("unknown"); err == nil {
+			t.Error("expected an error for unknown id, but got nil")
+		}
+	}
+	if backend.calls != 2 {
+		t.Errorf("unexpected calls: %d", backend.calls)
+	}
+	now = now.Add(time.Second)
+	s.GetCredential("unknown")
+	if backend.calls != 3 {
+		t.Errorf("unexpected calls: %d", backend.calls)
+	}
+
+	// the least recently used entry is evicted: "unknown" is the most recent, then "a".
+	s.GetCredential("a")
+	s.GetCredential("b")
+	if s.Len() != 2 {
+		t.Errorf("unexpected length: %d", s.Len())
+	}
+	calls := backend.calls
+	s.GetCredential("a")
+	if backend.calls != calls {
+		t.Error("recently used entry is evicted")
+	}
+	s.GetCredential("unknown")
+	if backend.calls != calls+1 {
+		t.Error("least recently used entry is not evicted")
+	}
+
+	// expiration
+	now = now.Add(time.Minute)
+	s.GetCredential("a")
+	if backend.calls != calls+2 {
+		t.Error("expired entry is returned")
+	}
+
+	s.Invalidate("a")
+	s.GetCredential("a")
+	if backend.calls != calls+3 {
+		t.Error("invalidated entry is returned")
+	}
+	s.Purge()
+	if s.Len() != 0 {
+		t.Errorf("unexpected length: %d", s.Len())
+	}
+
+	// other errors are not cached
+	failing := credentialStoreFunc(func(id string) (*Credential, error) {
+		return nil, errors.New("unavailable")
+	})
+	s = NewCachedCredentialStore(failing, 0, time.Minute, time.Minute)
+	s.GetCredential("a")
+	if s.Len() != 0 {
+		t.Errorf("unexpected length: %d", s.Len())
+	}
+
+	// the size is bounded by default
+	s = NewCachedCredentialStore(backend, 0, time.Minute, time.Minute)
+	for i := 0; i < DefaultCacheSize+10; i++ {
+		s.GetCredential("unknown-" + strconv.Itoa(i))
+	}
+	if s.Len() != DefaultCacheSize {
+		t.Errorf("unexpected length: %d", s.Len())
+	}
+}
+
+func TestCoalescingCredentialStore(t *testing.T) {
+	backend := newCountingCredentialStore()
+	backend.delay = 50 * time.Millisecond
+	s := NewCoalescingCredentialStore(backend)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c, err := s.GetCredential("a")
+			if err != nil || c.ID != "a" {
+				t.Errorf("unexpected credential: %v, %v", c, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if backend.calls != 1 {
+		t.Errorf("unexpected calls: %d", backend.calls)
+	}
+
+	// the next lookup calls the store again
+	s.GetCredential("a")
+	if backend.calls != 2 {
+		t.Errorf("unexpected calls: %d", backend.calls)
+	}
+}
+
+func TestChainCredentialStore(t *testing.T) {
+	first := &countingCredentialStore{creds: map[string]*Credential{"a": {ID: "a", Key: "first", Alg: SHA256}}}
+	second := newCountingCredentialStore()
+	s := ChainCredentialStore{first, second}
+
+	if c, _ := s.GetCredential("a"); c.Key != "first" {
+		t.Errorf("unexpected credential: %v", c)
+	}
+	if c, _ := s.GetCredential("b"); c.Key != "key-b" {
+		t.Errorf("unexpected credential: %v", c)
+	}
+	if _, err := s.GetCredential("unknown"); err != ErrCredentialNotFound {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if first.calls != 3 || second.calls != 2 {
+		t.Errorf("unexpected calls: %d, %d", first.calls, second.calls)
+	}
+
+	// the error of a failed store is returned when no other store has the credential
+	failing := credentialStoreFunc(func(id string) (*Credential, error) {
+		return nil, errors.New("unavailable")
+	})
+	s = ChainCredentialStore{failing, second}
+	if _, err := s.GetCredential("unknown"); err == nil || err.Error() != "unavailable" {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if c, _ := s.GetCredential("b"); c == nil || c.Key != "key-b" {
+		t.Errorf("unexpected credential: %v", c)
+	}
+
+	// composable
+	cached := NewCachedCredentialStore(NewCoalescingCredentialStore(s), 10, time.Minute, time.Second)
+	if c, err := cached.GetCredential("c"); err != nil || c.Key != "key-c" {
+		t.Errorf("unexpected credential: %v, %v", c, err)
+	}
+}
@@ -0,0 +1,240 @@
+package hawk
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// TargetResolver resolves the request target covered by the MAC.
+//
+// The returned URL must hold the scheme, the host (with an optional port), the path and the raw query
+// of the request as seen by the client. When the host has no port, the default port of the scheme is used.
+type TargetResolver interface {
+	ResolveTarget(req *http.Request) (*url.URL, error)
+}
+
+// DefaultTargetResolver resolves the target from the request itself.
+// The scheme is taken from the request URL, or derived from the TLS connection state.
+type DefaultTargetResolver struct{}
+
+func (r *DefaultTargetResolver) ResolveTarget(req *http.Request) (*url.URL, error) {
+	u := *req.URL
+	if req.Host != "" {
+		u.Host = req.Host
+	}
+	if u.Scheme == "" {
+		if req.TLS != nil {
+			u.Scheme = "https"
+		} else {
+			u.Scheme = "http"
+		}
+	}
+	return &u, nil
+}
+
+// HeaderTargetResolver resolves the target with values set by a proxy in custom headers.
+type HeaderTargetResolver struct {
+	// HostHeader is the name of the header holding the host (and port), e.g. "X-Forwarded-Host".
+	HostHeader string
+	// HostPort forces the host (and port).
+	HostPort string
+	// URIHeader is the name of the header holding the whole request URI.
+	// If set, the host is derived from the URI and HostHeader and HostPort are ignored.
+	URIHeader string
+}
+
+func (r *HeaderTargetResolver) ResolveTarget(req *http.Request) (*url.URL, error) {
+	u, _ := (&DefaultTargetResolver{}).ResolveTarget(req)
+
+	if r.URIHeader != "" {
+		cu, err := url.Parse(req.Header.Get(r.URIHeader))
+		if err != nil {
+			return nil, err
+		}
+		if cu.Host == "" {
+			cu.Host = u.Host
+		}
+		if cu.Scheme == "" {
+			cu.Scheme = u.Scheme
+		}
+		return cu, nil
+	}
+
+	if r.HostHeader != "" {
+		u.Host = req.Header.Get(r.HostHeader)
+	}
+	if r.HostPort != "" {
+		u.Host = r.HostPort
+	}
+	return u, nil
+}
+
+// ForwardedTargetResolver resolves the target from the RFC 7239 Forwarded header,
+// or from the X-Forwarded-Host, X-Forwarded-Proto and X-Forwarded-Port headers if it is absent.
+//
+// The headers are trusted only if the request comes from one of TrustedProxies.
+// If TrustedProxies is empty, the headers are not trusted, as any client could choose the target covered by the MAC.
+// Only the last element of each header is used: it is the one added by the trusted proxy,
+// the previous ones are sent by the client or by the proxies before it.
+type ForwardedTargetResolver struct {
+	TrustedProxies []*net.IPNet
+}
+
+// NewForwardedTargetResolver initializes a new ForwardedTargetResolver.
+// The trusted proxies are given as IP addresses or CIDR blocks. At least one is required.
+func NewForwardedTargetResolver(trustedProxies []string) (*ForwardedTargetResolver, error) {
+	if len(trustedProxies) == 0 {
+		return nil, errors.New("No trusted proxy.")
+	}
+	r := &ForwardedTargetResolver{}
+	for _, p := range trustedProxies {
+		if !strings.Contains(p, "/") {
+			ip := net.ParseIP(p)
+			if ip == nil {
+				return nil, errors.New("Invalid proxy address: " + p)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 8 * net.IPv4len
+			}
+			r.TrustedProxies = append(r.TrustedProxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(p)
+		if err != nil {
+			return nil, errors.New("Invalid proxy address: " + p)
+		}
+		r.TrustedProxies = append(r.TrustedProxies, n)
+	}
+	return r, nil
+}
+
+func (r *ForwardedTargetResolver) ResolveTarget(req *http.Request) (*url.URL, error) {
+	u, _ := (&DefaultTargetResolver{}).ResolveTarget(req)
+	if !r.trusted(req) {
+		return u, nil
+	}
+
+	var host, proto, port string
+	if fwd := lastValue(req.Header, "Forwarded"); fwd != "" {
+		host, proto = parseForwarded(fwd)
+	} else {
+		host = lastValue(req.Header, "X-Forwarded-Host")
+		proto = lastValue(req.Header, "X-Forwarded-Proto")
+		port = lastValue(req.Header, "X-Forwarded-Port")
+	}
+
+	if proto != "" {
+		u.Scheme = strings.ToLower(proto)
+	}
+	if host != "" {
+		u.Host = host
+	} else if proto != "" || port != "" {
+		// the port of the proxy to the upstream is meaningless for the client.
+		u.Host, _ = splitHostPort(u.Host)
+	}
+	if port != "" {
+		h, _ := splitHostPort(u.Host)
+		u.Host = net.JoinHostPort(strings.Trim(h, "[]"), port)
+	}
+	return u, nil
+}
+
+func (r *ForwardedTargetResolver) trusted(req *http.Request) bool {
+	ip := net.ParseIP(remoteAddr(req))
+	if ip == nil {
+		return false
+	}
+	for _, n := range r.TrustedProxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseForwarded returns the host and proto parameters of an element of a Forwarded header.
+func parseForwarded(v string) (host, proto string) {
+	for _, pair := range strings.Split(v, ";") {
+		i := strings.IndexByte(pair, '=')
+		if i < 0 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(pair[:i]))
+		value := strings.Trim(strings.TrimSpace(pair[i+1:]), `"`)
+		switch key {
+		case "host":
+			host = value
+		case "proto":
+			proto = value
+		}
+	}
+	return host, proto
+}
+
+// lastValue returns the last element of the comma-separated values of the header, over all its lines.
+func lastValue(h http.Header, name string) string {
+	values := h[http.CanonicalHeaderKey(name)]
+	if len(values) == 0 {
+		return ""
+	}
+	v := values[len(values)-1]
+	if i := strings.LastIndexByte(v, ','); i >= 0 {
+		v = v[i+1:]
+	}
+	return strings.TrimSpace(v)
+}
+
+// PrefixTargetResolver restores a path prefix which has been stripped by a gateway.
+type PrefixTargetResolver struct {
+	// Prefix is prepended to the path, e.g. "/api".
+	Prefix string
+	// Resolver resolves the target before the prefix is prepended.
+	// If nil, DefaultTargetResolver is used.
+	Resolver TargetResolver
+}
+
+func (r *PrefixTargetResolver) ResolveTarget(req *http.Request) (*url.URL, error) {
+	var resolver TargetResolver = &DefaultTargetResolver{}
+	if r.Resolver != nil {
+		resolver = r.Resolver
+	}
+	u, err := resolver.ResolveTarget(req)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := strings.TrimSuffix(r.Prefix, "/")
+	if prefix == "" {
+		return u, nil
+	}
+	if !strings.HasPrefix(prefix, "/") {
+		prefix = "/" + prefix
+	}
+	u.Path = prefix + u.Path
+	if u.RawPath != "" {
+		u.RawPath = prefix + u.RawPath
+	}
+	return u, nil
+}
+
+// targetResolver returns the TargetResolver of the server.
+// The deprecated custom options of AuthOption are mapped to a HeaderTargetResolver.
+func (s *Server) targetResolver() TargetResolver {
+	if s.TargetResolver != nil {
+		return s.TargetResolver
+	}
+	if o := s.AuthOption; o != nil &&
+		(o.CustomHostNameHeader != "" || o.CustomHostPort != "" || o.CustomURIHeader != "") {
+		return &HeaderTargetResolver{
+			HostHeader: o.CustomHostNameHeader,
+			HostPort:   o.CustomHostPort,
+			URIHeader:  o.CustomURIHeader,
+		}
+	}
+	return &DefaultTargetResolver{}
+}
@@ -0,0 +1,235 @@
+package hawk
+
+import (
+	"crypto/tls"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestDefaultTargetResolver(t *testing.T) {
+	r, _ := http.NewRequest("GET", "/resource/1?b=1&a=2", nil)
+	r.Host = "example.com:8080"
+
+	u, err := (&DefaultTargetResolver{}).ResolveTarget(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if act := u.String(); act != "http://example.com:8080/resource/1?b=1&a=2" {
+		t.Errorf("unexpected target: %s", act)
+	}
+
+	r.TLS = &tls.ConnectionState{}
+	u, _ = (&DefaultTargetResolver{}).ResolveTarget(r)
+	if u.Scheme != "https" {
+		t.Errorf("unexpected scheme: %s", u.Scheme)
+	}
+
+	// the request is not modified
+	if r.URL.Scheme != "" || r.URL.Host != "" {
+		t.Errorf("request url is modified: %s", r.URL)
+	}
+}
+
+func TestHeaderTargetResolver(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://www.example.com/resource/1?b=1&a=2", nil)
+	r.Header.Set("X-CUSTOM-HOST", "example.com:8080")
+	r.Header.Set("X-CUSTOM-URI", "https://api.example.com/login/1?a=2")
+
+	for _, tc := range []struct {
+		name     string
+		resolver *HeaderTargetResolver
+		expect   string
+	}{
+		{
+			name:     "host header",
+			resolver: &HeaderTargetResolver{HostHeader: "X-CUSTOM-HOST"},
+			expect:   "http://example.com:8080/resource/1?b=1&a=2",
+		},
+		{
+			name:     "host port",
+			resolver: &HeaderTargetResolver{HostHeader: "X-CUSTOM-HOST", HostPort: "b.example.com:8888"},
+			expect:   "http://b.example.com:8888/resource/1?b=1&a=2",
+		},
+		{
+			name:     "uri header takes precedence",
+			resolver: &HeaderTargetResolver{HostHeader: "X-CUSTOM-HOST", URIHeader: "X-CUSTOM-URI"},
+			expect:   "https://api.example.com/login/1?a=2",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			u, err := tc.resolver.ResolveTarget(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if act := u.String(); act != tc.expect {
+				t.Errorf("unexpected target: actual=%s, expect=%s", act, tc.expect)
+			}
+		})
+	}
+
+	r.Header.Set("X-CUSTOM-URI", "http://[::1")
+	if _, err := (&HeaderTargetResolver{URIHeader: "X-CUSTOM-URI"}).ResolveTarget(r); err == nil {
+		t.Error("expected an error for invalid uri, but got nil")
+	}
+}
+
+func TestForwardedTargetResolver(t *testing.T) {
+	resolver, err := NewForwardedTargetResolver([]string{"10.0.0.0/8", "192.0.2.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		expect     string
+	}{
+		{
+			name:       "forwarded",
+			remoteAddr: "10.1.2.3:5678",
+			headers:    map[string]string{"Forwarded": `for=198.51.100.17;proto=https;host="api.example.com"`},
+			expect:     "https://api.example.com/resource/1?a=1",
+		},
+		{
+			// the client sends its own element, the trusted proxy appends the last one.
+			name:       "forwarded by the client",
+			remoteAddr: "10.1.2.3:5678",
+			headers:    map[string]string{"Forwarded": `proto=http;host=evil.example.com, for=198.51.100.17;proto=https;host="api.example.com"`},
+			expect:     "https://api.example.com/resource/1?a=1",
+		},
+		{
+			name:       "x-forwarded",
+			remoteAddr: "192.0.2.1:5678",
+			headers: map[string]string{
+				"X-Forwarded-Host":  "evil.example.com, api.example.com",
+				"X-Forwarded-Proto": "https",
+				"X-Forwarded-Port":  "8443",
+			},
+			expect: "https://api.example.com:8443/resource/1?a=1",
+		},
+		{
+			name:       "x-forwarded-proto only",
+			remoteAddr: "192.0.2.1:5678",
+			headers:    map[string]string{"X-Forwarded-Proto": "https"},
+			expect:     "https://backend/resource/1?a=1",
+		},
+		{
+			name:       "untrusted proxy",
+			remoteAddr: "198.51.100.1:5678",
+			headers:    map[string]string{"Forwarded": `proto=https;host=api.example.com`},
+			expect:     "http://backend:8080/resource/1?a=1",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r, _ := http.NewRequest("GET", "/resource/1?a=1", nil)
+			r.Host = "backend:8080"
+			r.RemoteAddr = tc.remoteAddr
+			for k, v := range tc.headers {
+				r.Header.Set(k, v)
+			}
+
+			u, err := resolver.ResolveTarget(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if act := u.String(); act != tc.expect {
+				t.Errorf("unexpected target: actual=%s, expect=%s", act, tc.expect)
+			}
+		})
+	}
+
+	// the proxy adds its header line after the one of the client
+	r, _ := http.NewRequest("GET", "/resource/1?a=1", nil)
+	r.Host = "backend:8080"
+	r.RemoteAddr = "192.0.2.1:5678"
+	r.Header.Add("X-Forwarded-Host", "evil.example.com")
+	r.Header.Add("X-Forwarded-Host", "api.example.com")
+	if u, _ := resolver.ResolveTarget(r); u.String() != "http://api.example.com/resource/1?a=1" {
+		t.Errorf("unexpected target %s", u)
+	}
+
+	if _, err := NewForwardedTargetResolver([]string{"not-an-address"}); err == nil {
+		t.Error("expected an error for invalid proxy address, but got nil")
+	}
+	if _, err := NewForwardedTargetResolver(nil); err == nil {
+		t.Error("expected an error without trusted proxy, but got nil")
+	}
+
+	// the headers are not trusted without TrustedProxies
+	r, _ = http.NewRequest("GET", "/resource/1?a=1", nil)
+	r.Host = "backend:8080"
+	r.RemoteAddr = "10.1.2.3:5678"
+	r.Header.Set("Forwarded", "proto=https;host=api.example.com")
+	u, _ := (&ForwardedTargetResolver{}).ResolveTarget(r)
+	if act := u.String(); act != "http://backend:8080/resource/1?a=1" {
+		t.Errorf("unexpected target: %s", act)
+	}
+}
+
+func TestPrefixTargetResolver(t *testing.T) {
+	r, _ := http.NewRequest("GET", "/resource/x%2Fy?a=1", nil)
+	r.Host = "example.com"
+
+	u, err := (&PrefixTargetResolver{Prefix: "/api/v1/"}).ResolveTarget(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if act := u.String(); act != "http://example.com/api/v1/resource/x%2Fy?a=1" {
+		t.Errorf("unexpected target: %s", act)
+	}
+}
+
+func TestServer_TargetResolver(t *testing.T) {
+	credentialStore := &testCredentialStore{
+		ID:  "123456",
+		Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn",
+		Alg: SHA256,
+	}
+	cred := &Credential{ID: credentialStore.ID, Key: credentialStore.Key, Alg: credentialStore.Alg}
+
+	// the client talks to the gateway, which forwards /api/resource to the backend as /resource.
+	c := NewClient(cred, &Option{TimeStamp: time.Now().Unix(), Nonce: "3hOHpR"})
+	h, _ := c.Header("GET", "https://api.example.com/api/resource?a=1")
+
+	r, _ := http.NewRequest("GET", "/resource?a=1", nil)
+	r.Host = "backend:8080"
+	r.RemoteAddr = "10.0.0.1:5678"
+	r.Header.Set("Authorization", h)
+	r.Header.Set("X-Forwarded-Host", "api.example.com")
+	r.Header.Set("X-Forwarded-Proto", "https")
+
+	forwarded, _ := NewForwardedTargetResolver([]string{"10.0.0.0/8"})
+	s := NewServer(credentialStore)
+	s.TargetResolver = &PrefixTargetResolver{Prefix: "/api", Resolver: forwarded}
+
+	if _, err := s.Authenticate(r); err != nil {
+		t.Fatalf("return error, %s", err)
+	}
+
+	// the response is signed for the same target
+	sh, err := s.Header(r, cred, &Option{})
+	if err != nil {
+		t.Fatalf("return error, %s", err)
+	}
+	res := &http.Response{
+		Header: http.Header{"Server-Authorization": []string{sh}},
+	}
+	res.Request, _ = http.NewRequest("GET", "https://api.example.com/api/resource?a=1", nil)
+	if ok, err := c.Authenticate(res); !ok {
+		t.Errorf("failed to authenticate server response, %v", err)
+	}
+
+	// bewit
+	b := NewBewitConfig(cred, time.Minute)
+	bewit := b.GetBewit("https://api.example.com/api/resource?a=1", nil)
+	r1, _ := http.NewRequest("GET", "/resource?a=1&bewit="+bewit, nil)
+	r1.Host = "backend:8080"
+	r1.RemoteAddr = "10.0.0.1:5678"
+	r1.Header.Set("X-Forwarded-Host", "api.example.com")
+	r1.Header.Set("X-Forwarded-Proto", "https")
+	if _, err := s.AuthenticateBewit(r1); err != nil {
+		t.Errorf("return error, %s", err)
+	}
+}
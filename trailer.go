@@ -0,0 +1,233 @@
+package hawk
+
+import (
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// PayloadTrailer is the trailer which carries the payload hash of a streamed body, when the hash is not known
+// as the header is sent. Its value has the hash and a MAC binding it to the artifacts of the request, e.g.
+//
+//	Hawk-Payload: Hawk hash="Yi9LfIIFRtBEPt74PVmbTF/xVAwPn7ub15ePICfgnuY=", mac="..."
+//
+// The MAC covers the normalized string of the request with the hash, with the type "hawk.1.trailer"
+// for a request body, and "hawk.1.response-trailer" for a response body.
+const PayloadTrailer = "Hawk-Payload"
+
+const (
+	requestTrailer  = "hawk.1.trailer"
+	responseTrailer = "hawk.1.response-trailer"
+)
+
+// trailerMac returns the MAC of the PayloadTrailer for the request artifacts and the payload hash.
+func trailerMac(typ string, cred *Credential, u *url.URL, method string, artifacts *Option, hash string) ([]byte, error) {
+	opt := *artifacts
+	opt.Hash = hash
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+	writeNormalizedType(buf, typ, u, method, "", &opt)
+
+	return sign(cred, buf.Bytes())
+}
+
+// trailerValue returns the value of the PayloadTrailer for the payload hash.
+func trailerValue(typ string, cred *Credential, u *url.URL, method string, artifacts *Option, hash []byte) (string, error) {
+	h := base64.StdEncoding.EncodeToString(hash)
+	mac, err := trailerMac(typ, cred, u, method, artifacts, h)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString("Hawk ")
+	writeHeaderAttr(&b, "hash", h)
+	writeHeaderAttr(&b, "mac", base64.StdEncoding.EncodeToString(mac))
+	return b.String(), nil
+}
+
+// verifyTrailer verifies the value of the PayloadTrailer against the hash of the payload which has been read.
+func verifyTrailer(value, typ string, cred *Credential, u *url.URL, method string, artifacts *Option, hash []byte) error {
+	attrs := parseHawkHeader(value)
+	if attrs["hash"] == "" || attrs["mac"] == "" {
+		return errors.New("Missing payload trailer.")
+	}
+
+	mac, err := trailerMac(typ, cred, u, method, artifacts, attrs["hash"])
+	if err != nil {
+		return errors.New("Failed to calculate MAC.")
+	}
+	if !macEqual(mac, attrs["mac"]) {
+		return errors.New("Bad payload trailer mac.")
+	}
+	if !macEqual(hash, attrs["hash"]) {
+		return errors.New("Bad payload hash.")
+	}
+	return nil
+}
+
+// trailerReader hashes a body as it is read, and verifies the PayloadTrailer once it has been read:
+// Read returns the error of the verification instead of io.EOF.
+type trailerReader struct {
+	body   io.ReadCloser
+	hasher *payloadHasher
+	verify func(hash []byte) error
+	err    error
+}
+
+func (r *trailerReader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	n, err := r.body.Read(p)
+	r.hasher.Write(p[:n])
+	if err == io.EOF {
+		if verr := r.verify(r.hasher.Sum()); verr != nil {
+			err = verr
+		}
+	}
+	if err != nil {
+		r.err = err
+	}
+	return n, err
+}
+
+func (r *trailerReader) Close() error {
+	return r.body.Close()
+}
+
+// trailerWriter hashes a body as it is sent, and sets its trailer once it has been read.
+type trailerWriter struct {
+	body   io.ReadCloser
+	hasher *payloadHasher
+	set    func(hash []byte) error
+}
+
+func (w *trailerWriter) Read(p []byte) (int, error) {
+	n, err := w.body.Read(p)
+	w.hasher.Write(p[:n])
+	if err == io.EOF {
+		if serr := w.set(w.hasher.Sum()); serr != nil {
+			err = serr
+		}
+	}
+	return n, err
+}
+
+func (w *trailerWriter) Close() error {
+	return w.body.Close()
+}
+
+// SignTrailer signs the request with the Authorization header, without a payload hash,
+// and sends the payload hash of its body in the PayloadTrailer, so that the body is streamed without being buffered.
+// The hash covers the Content-Type of the request. The request is sent with chunked encoding.
+func (c *Client) SignTrailer(req *http.Request) error {
+	opt := *c.Option
+	opt.Hash, opt.Payload, opt.ContentType = "", "", ""
+
+	u := req.URL
+	h, err := NewClient(c.Credential, &opt).Header(req.Method, u.String())
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", h)
+
+	if req.Trailer == nil {
+		req.Trailer = http.Header{}
+	}
+	req.Trailer[PayloadTrailer] = nil
+	body := req.Body
+	if body == nil {
+		body = http.NoBody
+	}
+	req.Body = &trailerWriter{
+		body:   body,
+		hasher: newPayloadHasher(c.Credential.Alg, req.Header.Get("Content-Type")),
+		set: func(hash []byte) error {
+			v, err := trailerValue(requestTrailer, c.Credential, u, req.Method, &opt, hash)
+			if err != nil {
+				return err
+			}
+			req.Trailer.Set(PayloadTrailer, v)
+			return nil
+		},
+	}
+	req.ContentLength = -1
+	req.GetBody = nil
+	return nil
+}
+
+// VerifyTrailer prepares the body of the response, whose payload hash is sent in the PayloadTrailer,
+// to be verified as it is read: once the body has been read, Read returns an error instead of io.EOF
+// if the trailer is missing or does not match. The Server-Authorization header is verified with Authenticate.
+func (c *Client) VerifyTrailer(res *http.Response) error {
+	if _, ok := res.Trailer[PayloadTrailer]; !ok {
+		return errors.New("Missing payload trailer.")
+	}
+	if res.Request == nil {
+		return errors.New("Missing request of the response.")
+	}
+
+	u, method := res.Request.URL, res.Request.Method
+	res.Body = &trailerReader{
+		body:   res.Body,
+		hasher: newPayloadHasher(c.Credential.Alg, res.Header.Get("Content-Type")),
+		verify: func(hash []byte) error {
+			return verifyTrailer(res.Trailer.Get(PayloadTrailer), responseTrailer, c.Credential, u, method, c.Option, hash)
+		},
+	}
+	return nil
+}
+
+// VerifyTrailer prepares the body of a request authenticated with the Authorization header,
+// whose payload hash is sent in the PayloadTrailer, to be verified as it is read:
+// once the body has been read, Read returns an error instead of io.EOF if the trailer is missing or does not match.
+// It returns an error if the request does not announce the trailer.
+func (s *Server) VerifyTrailer(req *http.Request, cred *Credential, artifacts *Option) error {
+	if !hasPayloadTrailer(req) {
+		return s.fail(Header, cred.Alg, ReasonMissingHash, "Missing payload trailer.")
+	}
+	u, err := s.targetResolver().ResolveTarget(req)
+	if err != nil {
+		return s.fail(Header, cred.Alg, ReasonInvalidTarget, "Invalid request target.")
+	}
+
+	req.Body = &trailerReader{
+		body:   req.Body,
+		hasher: newPayloadHasher(cred.Alg, req.Header.Get("Content-Type")),
+		verify: func(hash []byte) error {
+			err := verifyTrailer(req.Trailer.Get(PayloadTrailer), requestTrailer, cred, u, req.Method, artifacts, hash)
+			if err != nil {
+				return s.fail(Header, cred.Alg, ReasonBadHash, err.Error())
+			}
+			return nil
+		},
+	}
+	return nil
+}
+
+// verifiesTrailer reports whether the body of the request is verified against the PayloadTrailer as it is read,
+// i.e. whether Server.VerifyTrailer has been called.
+func verifiesTrailer(req *http.Request) bool {
+	_, ok := req.Body.(*trailerReader)
+	return ok
+}
+
+// hasPayloadTrailer reports whether the request announces the PayloadTrailer.
+func hasPayloadTrailer(req *http.Request) bool {
+	_, ok := req.Trailer[PayloadTrailer]
+	return ok
+}
+
+// payloadTrailerValue returns the value of the PayloadTrailer of a response to the request.
+func (s *Server) payloadTrailerValue(req *http.Request, cred *Credential, artifacts *Option, hash []byte) (string, error) {
+	u, err := s.targetResolver().ResolveTarget(req)
+	if err != nil {
+		return "", errors.New("Invalid request target.")
+	}
+	return trailerValue(responseTrailer, cred, u, req.Method, artifacts, hash)
+}
@@ -0,0 +1,266 @@
+package hawk
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPayloadTrailer(t *testing.T) {
+	cred := &Credential{ID: "123456", Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn", Alg: SHA256}
+	s := NewServer(&staticCredentialStore{cred})
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, "received ")
+		w.(http.Flusher).Flush()
+		w.Write(body)
+	})
+	rs := NewResponseSigner(s)
+	rs.Stream = true
+	rs.PayloadTrailer = true
+	ts := httptest.NewServer(NewAuthorizer(s, Rule{RequirePayloadHash: true}).Handler(rs.Handler(handler)))
+	defer ts.Close()
+
+	do := func(body io.Reader, tamper func(req *http.Request)) (*Client, *http.Response) {
+		c := NewClient(cred, &Option{TimeStamp: time.Now().Unix(), Nonce: "j4h3g2", Ext: "some-app-ext-data"})
+		req, _ := http.NewRequest("PUT", ts.URL+"/upload?a=1", body)
+		req.Header.Set("Content-Type", "text/plain")
+		if err := c.SignTrailer(req); err != nil {
+			t.Fatal(err)
+		}
+		if tamper != nil {
+			tamper(req)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c, res
+	}
+
+	c, res := do(strings.NewReader("Thank you for flying Hawk"), nil)
+	if res.StatusCode != http.StatusOK {
+		b, _ := ioutil.ReadAll(res.Body)
+		t.Fatalf("unexpected response %d %s", res.StatusCode, b)
+	}
+	if ok, err := c.Authenticate(res); !ok {
+		t.Fatalf("expected a signed response header, got %v", err)
+	}
+	if err := c.VerifyTrailer(res); err != nil {
+		t.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil || string(body) != "received Thank you for flying Hawk" {
+		t.Errorf("unexpected body %q, %v", body, err)
+	}
+
+	// the trailer does not match the body which has been sent
+	_, res = do(strings.NewReader("Thank you for flying Hawk"), func(req *http.Request) {
+		tw := req.Body.(*trailerWriter)
+		set := tw.set
+		tw.set = func(hash []byte) error {
+			set(hash)
+			req.Trailer.Set(PayloadTrailer, strings.Replace(req.Trailer.Get(PayloadTrailer), "hash=\"", "hash=\"x", 1))
+			return nil
+		}
+	})
+	b, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest || !strings.Contains(string(b), "Bad payload trailer mac.") {
+		t.Errorf("unexpected response %d %s", res.StatusCode, b)
+	}
+
+	// a request without payload hash nor trailer
+	req, _ := http.NewRequest("PUT", ts.URL+"/upload", strings.NewReader("data"))
+	h, _ := NewClient(cred, &Option{TimeStamp: time.Now().Unix(), Nonce: "j4h3g2"}).Header("PUT", req.URL.String())
+	req.Header.Set("Authorization", h)
+	res, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401, got %d", res.StatusCode)
+	}
+}
+
+func TestServer_VerifyTrailer(t *testing.T) {
+	cred := &Credential{ID: "123456", Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn", Alg: SHA256}
+	other := &Credential{ID: "123456", Key: "another-key", Alg: SHA256}
+	artifacts := &Option{TimeStamp: 1353832234, Nonce: "j4h3g2", Ext: "some-app-ext-data"}
+	u, _ := url.Parse("http://example.com:8000/resource/1?b=1&a=2")
+
+	hash := (&PayloadHash{ContentType: "text/plain", Payload: "Thank you for flying Hawk", Alg: SHA256}).hash()
+	value, _ := trailerValue(requestTrailer, cred, u, "POST", artifacts, hash)
+	badMac, _ := trailerValue(requestTrailer, other, u, "POST", artifacts, hash)
+	response, _ := trailerValue(responseTrailer, cred, u, "POST", artifacts, hash)
+
+	for _, tc := range []struct {
+		name    string
+		body    string
+		trailer http.Header
+		expect  string
+	}{
+		{name: "valid", body: "Thank you for flying Hawk", trailer: http.Header{PayloadTrailer: {value}}},
+		{name: "bad hash", body: "Thank you for flying", trailer: http.Header{PayloadTrailer: {value}}, expect: "Bad payload hash."},
+		{name: "bad mac", body: "Thank you for flying Hawk", trailer: http.Header{PayloadTrailer: {badMac}}, expect: "Bad payload trailer mac."},
+		{name: "response trailer", body: "Thank you for flying Hawk", trailer: http.Header{PayloadTrailer: {response}}, expect: "Bad payload trailer mac."},
+		{name: "missing value", body: "Thank you for flying Hawk", trailer: http.Header{PayloadTrailer: nil}, expect: "Missing payload trailer."},
+	} {
+		s := NewServer(&staticCredentialStore{cred})
+		req := httptest.NewRequest("POST", u.String(), strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+		req.Trailer = tc.trailer
+
+		if err := s.VerifyTrailer(req, cred, artifacts); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		_, err := ioutil.ReadAll(req.Body)
+		if act := errString(err); act != tc.expect {
+			t.Errorf("%s: actual=%q, expect=%q", tc.name, act, tc.expect)
+		}
+	}
+
+	s := NewServer(&staticCredentialStore{cred})
+	req := httptest.NewRequest("POST", u.String(), strings.NewReader("data"))
+	if err := s.VerifyTrailer(req, cred, artifacts); err == nil {
+		t.Error("expected an error for a request without trailer")
+	}
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func TestClient_VerifyTrailer(t *testing.T) {
+	cred := &Credential{ID: "123456", Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn", Alg: SHA256}
+	opt := &Option{TimeStamp: 1353832234, Nonce: "j4h3g2"}
+	req := httptest.NewRequest("GET", "http://example.com:8000/resource/1", nil)
+
+	hash := (&PayloadHash{ContentType: "text/plain", Payload: "some reply", Alg: SHA256}).hash()
+	value, _ := trailerValue(responseTrailer, cred, req.URL, "GET", opt, hash)
+
+	for body, expect := range map[string]string{"some reply": "", "another reply": "Bad payload hash."} {
+		res := &http.Response{
+			Header:  http.Header{"Content-Type": {"text/plain"}},
+			Trailer: http.Header{PayloadTrailer: {value}},
+			Body:    ioutil.NopCloser(strings.NewReader(body)),
+			Request: req,
+		}
+		if err := NewClient(cred, opt).VerifyTrailer(res); err != nil {
+			t.Fatal(err)
+		}
+		_, err := ioutil.ReadAll(res.Body)
+		if act := errString(err); act != expect {
+			t.Errorf("%s: actual=%q, expect=%q", body, act, expect)
+		}
+	}
+
+	res := &http.Response{Header: http.Header{}, Body: ioutil.NopCloser(strings.NewReader("")), Request: req}
+	if err := NewClient(cred, opt).VerifyTrailer(res); err == nil {
+		t.Error("expected an error for a response without trailer")
+	}
+	res = &http.Response{Header: http.Header{}, Trailer: http.Header{PayloadTrailer: {value}}, Body: ioutil.NopCloser(strings.NewReader(""))}
+	if err := NewClient(cred, opt).VerifyTrailer(res); err == nil {
+		t.Error("expected an error for a response without request")
+	}
+}
+
+func TestAuthorizer_PayloadTrailer(t *testing.T) {
+	cred := &Credential{ID: "123456", Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn", Alg: SHA256}
+	a := NewAuthorizer(NewServer(&staticCredentialStore{cred}), Rule{RequirePayloadHash: true})
+	a.MaxBodySize = 64
+
+	var received *string
+	ts := httptest.NewServer(a.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		s := string(body)
+		received = &s
+	})))
+	defer ts.Close()
+
+	for _, tc := range []struct {
+		name   string
+		sign   func(c *Client, req *http.Request)
+		body   string
+		status int
+	}{
+		{
+			name:   "trailer",
+			sign:   func(c *Client, req *http.Request) { c.SignTrailer(req) },
+			body:   "Thank you for flying Hawk",
+			status: http.StatusOK,
+		},
+		{
+			name: "tampered trailer",
+			sign: func(c *Client, req *http.Request) {
+				c.SignTrailer(req)
+				tw := req.Body.(*trailerWriter)
+				tw.set = func(hash []byte) error {
+					req.Trailer.Set(PayloadTrailer, `Hawk hash="x", mac="x"`)
+					return nil
+				}
+			},
+			body:   "Thank you for flying Hawk",
+			status: http.StatusUnauthorized,
+		},
+		{
+			// a hash in the header is verified against the body, even if the request announces a trailer.
+			name: "hash in the header",
+			sign: func(c *Client, req *http.Request) {
+				c.Option.ContentType, c.Option.Payload = "text/plain", "Thank you"
+				h, _ := c.Header(req.Method, req.URL.String())
+				req.Header.Set("Authorization", h)
+				req.Trailer = http.Header{PayloadTrailer: nil}
+				req.ContentLength = -1
+			},
+			body:   "Thank you for flying Hawk",
+			status: http.StatusUnauthorized,
+		},
+	} {
+		received = nil
+		c := NewClient(cred, &Option{TimeStamp: time.Now().Unix(), Nonce: "j4h3g2"})
+		req, _ := http.NewRequest("PUT", ts.URL+"/upload", strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "text/plain")
+		tc.sign(c, req)
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+
+		if res.StatusCode != tc.status {
+			t.Errorf("%s: expected %d, got %d", tc.name, tc.status, res.StatusCode)
+		}
+		if tc.status == http.StatusOK && (received == nil || *received != tc.body) {
+			t.Errorf("%s: unexpected body %v", tc.name, received)
+		}
+		if tc.status != http.StatusOK && received != nil {
+			t.Errorf("%s: expected the request not to be handed over", tc.name)
+		}
+	}
+
+	// the trailer is not verified by Authenticate, so it does not satisfy RequirePayloadHash.
+	req := httptest.NewRequest("PUT", "http://example.com/upload", strings.NewReader("data"))
+	h, _ := NewClient(cred, &Option{TimeStamp: time.Now().Unix(), Nonce: "k3j4h2"}).Header("PUT", req.URL.String())
+	req.Header.Set("Authorization", h)
+	req.Trailer = http.Header{PayloadTrailer: nil}
+	if _, err := a.Authenticate(req); errString(err) != "Missing required payload hash." {
+		t.Errorf("unexpected error %v", err)
+	}
+}
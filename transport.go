@@ -0,0 +1,198 @@
+package hawk
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"time"
+)
+
+// Transport is an http.RoundTripper which signs the requests with the credential of their host,
+// as Client.Header does, and verifies the Server-Authorization header of the responses with Client.VerifyResponse.
+// The host is taken from the Host of the request if it is set, as it is sent in the Host header, or from its URL.
+//
+// The request body is buffered, and covered by the payload hash if the request has a Content-Type.
+// The response body is buffered too, and verified if the response has a payload hash.
+// A body larger than MaxBufferSize fails the round trip.
+// An unsigned 401 response, e.g. to a request with a stale timestamp, is returned as is.
+type Transport struct {
+	// Credentials are the credentials by host, e.g. "api.example.com" or "api.example.com:8443".
+	// A host with a port takes precedence over the host name alone. A request to another host fails.
+	Credentials map[string]*Credential
+	// Base sends the signed requests. If nil, http.DefaultTransport is used.
+	Base http.RoundTripper
+	// SkipResponseVerification returns the responses without verifying them.
+	SkipResponseVerification bool
+	// MaxBufferSize is the maximum size of a buffered body. If 0, DefaultMaxBufferSize is used.
+	MaxBufferSize int64
+}
+
+// ResponseVerificationError is returned by Transport when a response is not verified.
+type ResponseVerificationError struct {
+	Err error
+}
+
+func (e *ResponseVerificationError) Error() string {
+	return "Response verification failed: " + e.Err.Error()
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	u := signedURL(req)
+	cred, ok := t.Credentials[u.Host]
+	if !ok {
+		cred, ok = t.Credentials[u.Hostname()]
+	}
+	if !ok {
+		closeBody(req)
+		return nil, errors.New("No credential for " + u.Host + ".")
+	}
+
+	limit := maxBufferSize(t.MaxBufferSize)
+	body, err := readBody(req, limit)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := Nonce(6)
+	if err != nil {
+		return nil, err
+	}
+	opt := &Option{
+		TimeStamp:   time.Now().Unix(),
+		Nonce:       nonce,
+		ContentType: req.Header.Get("Content-Type"),
+		Payload:     string(body),
+	}
+	h, err := NewClient(cred, opt).Header(req.Method, u.String())
+	if err != nil {
+		return nil, err
+	}
+
+	out := req.Clone(req.Context())
+	out.Header.Set("Authorization", h)
+	if req.Body != nil {
+		out.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	res, err := t.base().RoundTrip(out)
+	if err != nil || t.SkipResponseVerification {
+		return res, err
+	}
+	if res.Header.Get("Server-Authorization") == "" && res.StatusCode == http.StatusUnauthorized {
+		return res, nil
+	}
+
+	resBody, err := readLimited(res.Body, limit, errResponseTooLarge)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = ioutil.NopCloser(bytes.NewReader(resBody))
+	if _, err := NewClient(cred, opt).verifyResponse(res, u, resBody); err != nil {
+		return nil, &ResponseVerificationError{Err: err}
+	}
+	return res, nil
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+// signedURL returns the URL of the request with the host of its Host header, which the server sees.
+func signedURL(req *http.Request) *url.URL {
+	if req.Host == "" || req.Host == req.URL.Host {
+		return req.URL
+	}
+	u := *req.URL
+	u.Host = req.Host
+	return &u
+}
+
+var (
+	errRequestTooLarge  = errors.New("Request body too large.")
+	errResponseTooLarge = errors.New("Response body too large.")
+)
+
+func readBody(req *http.Request, limit int64) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	defer req.Body.Close()
+	return readLimited(req.Body, limit, errRequestTooLarge)
+}
+
+// readLimited reads r up to limit bytes, and returns tooLarge if it is larger.
+func readLimited(r io.Reader, limit int64, tooLarge error) ([]byte, error) {
+	b, err := ioutil.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) > limit {
+		return nil, tooLarge
+	}
+	return b, nil
+}
+
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
+
+// SigningProxy is a forward proxy which signs the requests with a Transport,
+// for clients which cannot compute Hawk headers, e.g. shell scripts.
+// The clients send plain HTTP requests to the proxy, e.g. with the HTTP_PROXY environment variable.
+// CONNECT requests are rejected, as the requests in a tunnel cannot be signed.
+type SigningProxy struct {
+	Transport *Transport
+	// UpgradeToHTTPS forwards the requests with HTTPS, so that the TLS connection to the upstream is made by the proxy.
+	UpgradeToHTTPS bool
+	// Proxy forwards the requests. Its errors, including response verification failures, are answered with 502.
+	Proxy *httputil.ReverseProxy
+}
+
+// NewSigningProxy initializes a new SigningProxy which signs the requests with the credentials by host.
+func NewSigningProxy(creds map[string]*Credential) *SigningProxy {
+	p := &SigningProxy{Transport: &Transport{Credentials: creds}}
+	p.Proxy = &httputil.ReverseProxy{
+		Director:  p.direct,
+		Transport: p.Transport,
+		ErrorHandler: func(w http.ResponseWriter, req *http.Request, err error) {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+		},
+	}
+	return p
+}
+
+func (p *SigningProxy) direct(req *http.Request) {
+	// the Host header is taken from the URL, which is covered by the MAC.
+	req.Host = ""
+	if p.UpgradeToHTTPS && req.URL.Scheme == "http" {
+		req.URL.Scheme = "https"
+		if req.URL.Port() == "80" {
+			req.URL.Host = req.URL.Hostname()
+		}
+	}
+	// keep the default User-Agent of the client out of the request, as ReverseProxy does.
+	if _, ok := req.Header["User-Agent"]; !ok {
+		req.Header.Set("User-Agent", "")
+	}
+}
+
+func (p *SigningProxy) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method == http.MethodConnect {
+		http.Error(w, "CONNECT is not supported, as the requests in a tunnel cannot be signed.", http.StatusMethodNotAllowed)
+		return
+	}
+	if !req.URL.IsAbs() {
+		http.Error(w, "Not a proxy request.", http.StatusBadRequest)
+		return
+	}
+	p.Proxy.ServeHTTP(w, req)
+}
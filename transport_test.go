@@ -0,0 +1,167 @@
+package hawk
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestSigningProxy(t *testing.T) {
+	cred := &Credential{ID: "123456", Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn", Alg: SHA256}
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		s := NewServer(&staticCredentialStore{cred})
+		s.Payload = string(body)
+		c, err := s.Authenticate(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		reply := "some reply to " + string(body)
+		signed := reply
+		if string(body) == "tampered" {
+			signed = "some reply"
+		}
+		h, _ := s.Header(r, c, &Option{ContentType: "text/plain", Payload: signed})
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Server-Authorization", h)
+		fmt.Fprint(w, reply)
+	}))
+	defer upstream.Close()
+	upstreamURL, _ := url.Parse(upstream.URL)
+
+	p := NewSigningProxy(map[string]*Credential{upstreamURL.Host: cred})
+	ts := httptest.NewServer(p)
+	defer ts.Close()
+	proxyURL, _ := url.Parse(ts.URL)
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}
+
+	post := func(rawurl, body string) (int, string) {
+		res, err := client.Post(rawurl, "text/plain", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		b, _ := ioutil.ReadAll(res.Body)
+		return res.StatusCode, string(b)
+	}
+
+	if code, body := post(upstream.URL+"/resource?a=1", "hello"); code != http.StatusOK || body != "some reply to hello" {
+		t.Errorf("unexpected response %d %s", code, body)
+	}
+
+	// the hash of the response does not match the body
+	if code, body := post(upstream.URL+"/resource?a=1", "tampered"); code != http.StatusBadGateway ||
+		!strings.Contains(body, "Response verification failed") {
+		t.Errorf("unexpected response %d %s", code, body)
+	}
+
+	// no credential
+	if code, body := post("http://unknown.example.com/", ""); code != http.StatusBadGateway || !strings.Contains(body, "No credential") {
+		t.Errorf("unexpected response %d %s", code, body)
+	}
+
+	// a direct request to the proxy
+	res, err := http.Get(ts.URL + "/resource")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", res.StatusCode)
+	}
+
+	req, _ := http.NewRequest(http.MethodConnect, ts.URL, nil)
+	req.URL.Opaque = "example.com:443"
+	res, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", res.StatusCode)
+	}
+}
+
+func TestTransport_Unauthorized(t *testing.T) {
+	cred := &Credential{ID: "123456", Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn", Alg: SHA256}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("WWW-Authenticate", "Hawk")
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer upstream.Close()
+	u, _ := url.Parse(upstream.URL)
+
+	client := &http.Client{Transport: &Transport{Credentials: map[string]*Credential{u.Hostname(): cred}}}
+	res, err := client.Get(upstream.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401, got %d", res.StatusCode)
+	}
+}
+
+func TestTransport_Host(t *testing.T) {
+	cred := &Credential{ID: "123456", Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn", Alg: SHA256}
+	s := NewServer(&staticCredentialStore{cred})
+	upstream := httptest.NewServer(NewAuthorizer(s, Rule{}).Handler(NewResponseSigner(s).Handler(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, r.Host)
+		}),
+	)))
+	defer upstream.Close()
+
+	client := &http.Client{Transport: &Transport{Credentials: map[string]*Credential{"api.example.com": cred}}}
+	req, _ := http.NewRequest("GET", upstream.URL+"/resource", nil)
+	req.Host = "api.example.com"
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || string(body) != "api.example.com" {
+		t.Errorf("unexpected response %d %s", res.StatusCode, body)
+	}
+}
+
+func TestTransport_MaxBufferSize(t *testing.T) {
+	cred := &Credential{ID: "123456", Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn", Alg: SHA256}
+	s := NewServer(&staticCredentialStore{cred})
+	upstream := httptest.NewServer(NewAuthorizer(s, Rule{}).Handler(NewResponseSigner(s).Handler(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "some long reply")
+		}),
+	)))
+	defer upstream.Close()
+	u, _ := url.Parse(upstream.URL)
+
+	tr := &Transport{Credentials: map[string]*Credential{u.Hostname(): cred}, MaxBufferSize: 10}
+	client := &http.Client{Transport: tr}
+
+	if _, err := client.Post(upstream.URL, "text/plain", strings.NewReader("some long request")); err == nil ||
+		!strings.Contains(err.Error(), "Request body too large.") {
+		t.Errorf("expected request body too large, got %v", err)
+	}
+	if _, err := client.Get(upstream.URL); err == nil || !strings.Contains(err.Error(), "Response body too large.") {
+		t.Errorf("expected response body too large, got %v", err)
+	}
+
+	tr.MaxBufferSize = 15
+	res, err := client.Post(upstream.URL, "text/plain", strings.NewReader("short"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if string(body) != "some long reply" {
+		t.Errorf("unexpected body %s", body)
+	}
+}
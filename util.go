@@ -1,10 +1,13 @@
 package hawk
 
 import (
+	"crypto/hmac"
 	"crypto/rand"
+	"encoding/base64"
 	"encoding/hex"
-	"regexp"
+	"errors"
 	"strings"
+	"sync"
 )
 
 func Nonce(n int) (string, error) {
@@ -13,47 +16,137 @@ func Nonce(n int) (string, error) {
 	return hex.EncodeToString(bytes), err
 }
 
+// parseHawkHeader parses the attributes of a Hawk Authorization or Server-Authorization header value.
+// It returns an empty map if the value is not a well-formed Hawk header.
 func parseHawkHeader(headerVal string) map[string]string {
-	attrs := make(map[string]string)
+	const scheme = "Hawk"
 
-	if headerVal == "" {
-		return attrs
+	if len(headerVal) <= len(scheme) || !strings.EqualFold(headerVal[:len(scheme)], scheme) ||
+		!isSpace(headerVal[len(scheme)]) {
+		return map[string]string{}
 	}
 
-	h1 := strings.Split(headerVal, "Hawk ")
-	if len(h1) != 2 {
-		return attrs
-	}
-	h2 := strings.Split(h1[1], ", ")
+	attrs := make(map[string]string, 8)
+	s := headerVal[len(scheme):]
+	for {
+		s = trimLeftSpace(s)
+		if s == "" {
+			return attrs
+		}
+
+		// key
+		i := 0
+		for i < len(s) && isWordChar(s[i]) {
+			i++
+		}
+		if i == 0 || i+1 >= len(s) || s[i] != '=' || s[i+1] != '"' {
+			return map[string]string{}
+		}
+		key := s[:i]
+		s = s[i+2:]
+
+		// quoted value, which must not contain quotes or backslashes
+		i = 0
+		for i < len(s) && s[i] != '"' && s[i] != '\\' {
+			i++
+		}
+		if i == len(s) || s[i] != '"' {
+			return map[string]string{}
+		}
+		value := s[:i]
+		s = trimLeftSpace(s[i+1:])
+
+		if s != "" {
+			if s[0] != ',' {
+				return map[string]string{}
+			}
+			s = s[1:]
+		}
 
-	//FIXME: validate duplication, unknown-key
-	for _, v := range h2 {
-		r := regexp.MustCompile(`(\w+)="([^"\\]*)"\s*(?:,\s*|$)`)
-		group := r.FindSubmatch([]byte(v))
-		if len(group) != 3 {
-			// ignore. invalid structure
-			continue
+		if _, ok := attrs[key]; ok {
+			// duplicated attribute
+			return map[string]string{}
 		}
-		attrs[string(group[1])] = string(group[2])
+		attrs[key] = value
 	}
+}
 
-	return attrs
+// ParseHeader parses the attributes of a Hawk header value,
+// e.g. of an Authorization, Server-Authorization or WWW-Authenticate header.
+func ParseHeader(value string) (map[string]string, error) {
+	if strings.EqualFold(strings.TrimSpace(value), "Hawk") {
+		return map[string]string{}, nil
+	}
+	attrs := parseHawkHeader(value)
+	if len(attrs) == 0 {
+		return nil, errors.New("Invalid Hawk header.")
+	}
+	return attrs, nil
 }
 
-// compare strings using fixed time algorithm
-func fixedTimeComparison(str1, str2 string) bool {
-	var mismatch int32 = 1
-	if len(str1) == len(str2) {
-		mismatch = 0
+// writeHeaderAttr appends a key="value" attribute to a Hawk header value being built.
+func writeHeaderAttr(b *strings.Builder, key, value string) {
+	if b.Len() > len("Hawk ") {
+		b.WriteString(", ")
 	}
+	b.WriteString(key)
+	b.WriteString(`="`)
+	b.WriteString(value)
+	b.WriteByte('"')
+}
 
-	for i, _ := range str1 {
-		codeA := []rune(str1)[i]
-		codeB := []rune(str2)[i]
+func isWordChar(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '_'
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t'
+}
 
-		res := (codeA ^ codeB)
-		mismatch = mismatch | res
+func trimLeftSpace(s string) string {
+	for s != "" && isSpace(s[0]) {
+		s = s[1:]
 	}
+	return s
+}
+
+// macEqual reports whether the base64 encoded MAC supplied by the peer matches the expected one.
+// The comparison is done in constant time on the decoded bytes. The encoding must be canonical,
+// so that a MAC has a single encoding, e.g. to be remembered by a BewitRegistry.
+func macEqual(expected []byte, supplied string) bool {
+	decoded, err := base64.StdEncoding.Strict().DecodeString(supplied)
+	if err != nil {
+		decoded = nil
+	}
+	return hmac.Equal(expected, decoded)
+}
 
-	return mismatch == 0
+// canonicalMAC returns the canonical base64 encoding of a MAC, the one accepted by macEqual,
+// and reports whether mac is that encoding. The encodings which differ only in their padding bits
+// have the same canonical encoding. A MAC which is not valid base64 is returned as is.
+func canonicalMAC(mac string) (string, bool) {
+	decoded, err := base64.StdEncoding.DecodeString(mac)
+	if err != nil {
+		return mac, false
+	}
+	c := base64.StdEncoding.EncodeToString(decoded)
+	return c, c == mac
+}
+
+var (
+	decoyKeyOnce sync.Once
+	decoyKey     string
+)
+
+// decoyCredential returns a credential with a random key which is never given to anyone.
+// It is used in place of an unknown credential so that the MAC is still computed.
+func decoyCredential(id string, alg Alg) *Credential {
+	decoyKeyOnce.Do(func() {
+		decoyKey, _ = Nonce(32)
+	})
+	return &Credential{
+		ID:  id,
+		Key: decoyKey,
+		Alg: alg,
+	}
 }
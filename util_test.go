@@ -1,6 +1,7 @@
 package hawk
 
 import (
+	"reflect"
 	"testing"
 	"unicode/utf8"
 )
@@ -16,3 +17,67 @@ func Test_Nonce(t *testing.T) {
 		t.Error("expected length=10, but actual length=", utf8.RuneCountInString(act))
 	}
 }
+
+func Test_macEqual(t *testing.T) {
+	expect := []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06}
+
+	for _, tc := range []struct {
+		name     string
+		supplied string
+		result   bool
+	}{
+		{name: "same", supplied: "AQIDBAUG", result: true},
+		{name: "different", supplied: "AQIDBAUH", result: false},
+		{name: "shorter", supplied: "AQID", result: false},
+		{name: "longer", supplied: "AQIDBAUGBwgJ", result: false},
+		{name: "empty", supplied: "", result: false},
+		{name: "not base64", supplied: "AQID*AUG", result: false},
+		{name: "multibyte", supplied: "AQIDBAUあ", result: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if act := macEqual(expect, tc.supplied); act != tc.result {
+				t.Errorf("unexpected result: actual=%v, expect=%v", act, tc.result)
+			}
+		})
+	}
+}
+
+func Test_parseHawkHeader(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		header string
+		expect map[string]string
+	}{
+		{
+			name:   "valid",
+			header: `Hawk id="dh37fgj492je", ts="1353832234", nonce="j4h3g2", ext="some-app-ext-data", mac="6R4rV5iE+NPoym+WwjeHzjAGXUtLNIxmo1vpMofpLAE="`,
+			expect: map[string]string{
+				"id":    "dh37fgj492je",
+				"ts":    "1353832234",
+				"nonce": "j4h3g2",
+				"ext":   "some-app-ext-data",
+				"mac":   "6R4rV5iE+NPoym+WwjeHzjAGXUtLNIxmo1vpMofpLAE=",
+			},
+		},
+		{
+			name:   "loose spacing and lower-case scheme",
+			header: `hawk  mac="abc=",hash="def" ,  ext=""`,
+			expect: map[string]string{"mac": "abc=", "hash": "def", "ext": ""},
+		},
+		{name: "empty", header: "", expect: map[string]string{}},
+		{name: "other scheme", header: `Basic dXNlcjpwYXNz`, expect: map[string]string{}},
+		{name: "no attributes", header: `Hawk`, expect: map[string]string{}},
+		{name: "unquoted value", header: `Hawk id=abc`, expect: map[string]string{}},
+		{name: "unterminated value", header: `Hawk id="abc`, expect: map[string]string{}},
+		{name: "backslash in value", header: `Hawk id="a\"bc"`, expect: map[string]string{}},
+		{name: "missing comma", header: `Hawk id="a" ts="1"`, expect: map[string]string{}},
+		{name: "duplicated attribute", header: `Hawk id="a", id="b"`, expect: map[string]string{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			act := parseHawkHeader(tc.header)
+			if !reflect.DeepEqual(act, tc.expect) {
+				t.Errorf("unexpected attributes: actual=%v, expect=%v", act, tc.expect)
+			}
+		})
+	}
+}